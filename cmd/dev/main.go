@@ -0,0 +1,266 @@
+// Command dev runs the development loop without make or external tools:
+// templ and Tailwind in watch mode, and the server, rebuilt and restarted
+// whenever a .go file changes. Ctrl-C stops everything.
+//
+//	go run ./cmd/dev [flags] [-- server flags]
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/ankit-lilly/go-datastar-daisyui-template/internal/assets"
+	"github.com/fsnotify/fsnotify"
+)
+
+// debounce collapses bursts of file events, such as templ regenerating
+// every view, into one restart.
+const debounce = 300 * time.Millisecond
+
+// stopTimeout is how long a process gets to exit after an interrupt before
+// it is killed.
+const stopTimeout = 5 * time.Second
+
+func main() {
+	defaultStatic := os.Getenv("STATIC_DIR")
+	if defaultStatic == "" {
+		defaultStatic = "static"
+	}
+	staticDir := flag.String("static-dir", defaultStatic, "static directory containing css/tailwindcss (passed to the server as STATIC_DIR)")
+	flag.Usage = func() {
+		fmt.Fprintln(flag.CommandLine.Output(), "usage: dev [flags] [-- server flags]")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	cssDir := filepath.Join(*staticDir, "css")
+	if _, err := os.Stat(filepath.Join(cssDir, "tailwindcss")); err != nil {
+		fatal("Tailwind not found in %s; run 'go run ./cmd/install' first", cssDir)
+	}
+
+	// Tailwind writes the unhashed output.css, so drop the manifest to have
+	// the server link to it instead of a stale fingerprint.
+	if err := os.Remove(filepath.Join(*staticDir, assets.ManifestFile)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		fatal("Failed to remove manifest: %v", err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	out := &console{w: os.Stdout}
+
+	var wg sync.WaitGroup
+	watchers := []*exec.Cmd{
+		command(ctx, out, "templ", "", "go", "tool", "templ", "generate", "--watch"),
+		command(ctx, out, "css", cssDir, "./tailwindcss", "-i", "input.css", "-o", "output.css", "--watch"),
+	}
+	// Tailwind stops watching when stdin closes, so hand it ours.
+	watchers[1].Stdin = os.Stdin
+	for _, cmd := range watchers {
+		wg.Go(func() {
+			if err := cmd.Run(); err != nil && ctx.Err() == nil {
+				out.printf("dev", "%s exited: %v", cmd.Args[0], err)
+			}
+		})
+	}
+
+	srv := &server{out: out, args: flag.Args(), staticDir: *staticDir}
+	if err := watchGo(ctx, out, ".", srv.restart); err != nil {
+		fatal("Failed to watch files: %v", err)
+	}
+
+	wg.Wait()
+	srv.stop()
+	out.printf("dev", "stopped")
+}
+
+// command builds a subprocess whose output is prefixed with name and which
+// is interrupted, then killed, when ctx is cancelled.
+func command(ctx context.Context, out *console, name, dir string, args ...string) *exec.Cmd {
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+	cmd.Dir = dir
+	cmd.Stdout = out.writer(name)
+	cmd.Stderr = out.writer(name)
+	cmd.Cancel = func() error { return cmd.Process.Signal(os.Interrupt) }
+	cmd.WaitDelay = stopTimeout
+	return cmd
+}
+
+// server builds and runs cmd/server, replacing the running process on each
+// restart.
+type server struct {
+	out       *console
+	args      []string
+	staticDir string
+	cmd       *exec.Cmd
+	done      chan struct{}
+}
+
+func (s *server) restart() {
+	bin := filepath.Join(os.TempDir(), "go-datastar-dev-server")
+	s.out.printf("dev", "building server...")
+	build := exec.Command("go", "build", "-o", bin, "./cmd/server")
+	var stderr bytes.Buffer
+	build.Stderr = &stderr
+	if err := build.Run(); err != nil {
+		s.out.printf("build", "%s", strings.TrimSpace(stderr.String()))
+		s.out.printf("dev", "build failed; keeping the previous server, waiting for changes")
+		return
+	}
+
+	s.stop()
+
+	cmd := exec.Command(bin, s.args...)
+	cmd.Env = append(os.Environ(), "STATIC_DIR="+s.staticDir)
+	cmd.Stdout = s.out.writer("server")
+	cmd.Stderr = s.out.writer("server")
+	if err := cmd.Start(); err != nil {
+		s.out.printf("dev", "failed to start server: %v", err)
+		return
+	}
+	done := make(chan struct{})
+	go func() {
+		cmd.Wait()
+		close(done)
+	}()
+	s.cmd, s.done = cmd, done
+}
+
+// stop interrupts the running server, if any, and kills it if it hasn't
+// exited within stopTimeout.
+func (s *server) stop() {
+	if s.cmd == nil {
+		return
+	}
+	s.cmd.Process.Signal(os.Interrupt)
+	select {
+	case <-s.done:
+	case <-time.After(stopTimeout):
+		s.out.printf("dev", "server did not stop in %s, killing it", stopTimeout)
+		s.cmd.Process.Kill()
+		<-s.done
+	}
+	s.cmd = nil
+}
+
+// watchGo calls onChange once at start and again, debounced, whenever a
+// .go file under root is written, created, removed or renamed. It blocks
+// until ctx is cancelled.
+func watchGo(ctx context.Context, out *console, root string, onChange func()) error {
+	w, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	defer w.Close()
+
+	if err := addTree(w, root); err != nil {
+		return err
+	}
+
+	onChange()
+
+	var timer <-chan time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case ev, ok := <-w.Events:
+			if !ok {
+				return nil
+			}
+			// fsnotify doesn't recurse, so watch new directories as they
+			// appear.
+			if ev.Has(fsnotify.Create) {
+				if info, err := os.Stat(ev.Name); err == nil && info.IsDir() {
+					if err := addTree(w, ev.Name); err != nil {
+						out.printf("dev", "failed to watch %s: %v", ev.Name, err)
+					}
+					continue
+				}
+			}
+			if strings.HasSuffix(ev.Name, ".go") && !ev.Has(fsnotify.Chmod) {
+				timer = time.After(debounce)
+			}
+		case err, ok := <-w.Errors:
+			if !ok {
+				return nil
+			}
+			out.printf("dev", "watch error: %v", err)
+		case <-timer:
+			timer = nil
+			onChange()
+		}
+	}
+}
+
+// addTree watches root and every directory below it, skipping hidden
+// directories, static assets and vendored code.
+func addTree(w *fsnotify.Watcher, root string) error {
+	return filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || !d.IsDir() {
+			return err
+		}
+		name := d.Name()
+		if path != root && (strings.HasPrefix(name, ".") || name == "static" || name == "vendor" || name == "node_modules" || name == "bin") {
+			return filepath.SkipDir
+		}
+		return w.Add(path)
+	})
+}
+
+// console serialises prefixed lines from several processes onto one
+// writer.
+type console struct {
+	w  io.Writer
+	mu sync.Mutex
+}
+
+func (c *console) printf(prefix, format string, args ...any) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	fmt.Fprintf(c.w, "%-7s| %s\n", prefix, fmt.Sprintf(format, args...))
+}
+
+// writer returns a writer that prints each complete line it receives with
+// prefix.
+func (c *console) writer(prefix string) io.Writer {
+	return &lineWriter{console: c, prefix: prefix}
+}
+
+// lineWriter buffers partial lines until their newline arrives.
+type lineWriter struct {
+	console *console
+	prefix  string
+	buf     []byte
+}
+
+func (w *lineWriter) Write(p []byte) (int, error) {
+	w.buf = append(w.buf, p...)
+	for {
+		line, rest, ok := bytes.Cut(w.buf, []byte("\n"))
+		if !ok {
+			break
+		}
+		w.console.printf(w.prefix, "%s", bytes.TrimRight(line, "\r"))
+		w.buf = rest
+	}
+	return len(p), nil
+}
+
+func fatal(format string, args ...any) {
+	fmt.Fprintf(os.Stderr, "❌ "+format+"\n", args...)
+	os.Exit(1)
+}
@@ -1,29 +1,97 @@
 package main
 
 import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"crypto/sha512"
+	_ "embed"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
 	"errors"
+	"flag"
 	"fmt"
 	"io"
 	"net/http"
 	"os"
 	"os/exec"
+	"os/signal"
 	"path/filepath"
+	"regexp"
 	"runtime"
+	"slices"
 	"strings"
 	"sync"
+	"syscall"
+	"text/template"
+	"time"
+
+	"github.com/andybalholm/brotli"
+	"github.com/ankit-lilly/go-datastar-daisyui-template/internal/assets"
+	"github.com/ankit-lilly/go-datastar-daisyui-template/internal/buildinfo"
 )
 
 const (
 	tailwindBaseURL = "https://github.com/tailwindlabs/tailwindcss/releases/latest/download"
 	daisyUIBaseURL  = "https://github.com/saadeghi/daisyui/releases/latest/download"
-	datastarVersion = "v1.0.0"
+	datastarVersion = buildinfo.DatastarVersion
 	datastarURL     = "https://cdn.jsdelivr.net/gh/starfederation/datastar@" + datastarVersion + "/bundles/datastar.js"
+
+	// maxConcurrentDownloads bounds fan-out within a download step.
+	maxConcurrentDownloads = 4
 )
 
 func main() {
-	staticDir := "static"
-	if len(os.Args) > 1 {
-		staticDir = os.Args[1]
+	watch := flag.Bool("watch", false, "after setup, keep rebuilding CSS on changes (tailwindcss --watch)")
+	viewsGlob := flag.String("views-glob", "internal/views/**/*.templ", "templ files Tailwind scans for classes, relative to the project root")
+	precompress := flag.Bool("precompress", false, "also write Brotli (.br) and gzip (.gz) copies of the built CSS")
+	inputCSSTemplate := flag.String("input-css-template", "", "text/template file to generate input.css from instead of the built-in one")
+	flag.Usage = func() {
+		fmt.Fprintln(flag.CommandLine.Output(), "usage: install [flags] [static-dir]")
+		fmt.Fprintln(flag.CommandLine.Output(), "       install list-themes [static-dir]")
+		fmt.Fprintf(flag.CommandLine.Output(), "       install [flags] update <%s> [static-dir]\n", strings.Join(updatable, "|"))
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	// Ctrl-C cancels in-flight downloads and subprocesses; partial files are
+	// removed rather than left behind.
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	// staticDirArg is the optional static-dir argument after n others.
+	staticDirArg := func(n int) string {
+		if flag.NArg() > n {
+			return flag.Arg(n)
+		}
+		return "static"
+	}
+
+	switch flag.Arg(0) {
+	case "list-themes":
+		if err := listThemes(filepath.Join(staticDirArg(1), "css")); err != nil {
+			fatal("%v", err)
+		}
+		return
+	case "update":
+		if flag.NArg() < 2 {
+			fatal("update needs an asset: %s", strings.Join(updatable, ", "))
+		}
+		if err := update(ctx, flag.Arg(1), staticDirArg(2), *precompress); err != nil {
+			exitIfCancelled(ctx)
+			fatal("Update failed: %v", err)
+		}
+		fmt.Printf("\n✅ %s updated\n", flag.Arg(1))
+		return
+	}
+
+	staticDir, err := installDir(flag.Args())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+		flag.Usage()
+		os.Exit(2)
 	}
 
 	cssDir := filepath.Join(staticDir, "css")
@@ -39,46 +107,54 @@ func main() {
 
 	fmt.Printf("🚀 Setting up Go + Templ + Datastar + DaisyUI template for %s/%s\n\n", runtime.GOOS, runtime.GOARCH)
 
+	var datastarSRI string
+
 	// Run independent setup tasks concurrently to reduce total install time.
 	if err := runParallel(
 		task{
 			name: "go dependencies",
-			fn:   downloadDeps,
+			fn: func() error {
+				return downloadDeps(ctx)
+			},
 		},
 		task{
 			name: "tailwind",
 			fn: func() error {
-				return downloadTailwind(cssDir)
+				return downloadTailwind(ctx, cssDir)
 			},
 		},
 		task{
 			name: "daisyui",
 			fn: func() error {
-				return downloadDaisyUI(cssDir)
+				return downloadDaisyUI(ctx, cssDir)
 			},
 		},
 		task{
 			name: "datastar",
 			fn: func() error {
-				return downloadDatastar(jsDir)
+				sri, err := downloadDatastar(ctx, jsDir)
+				datastarSRI = sri
+				return err
 			},
 		},
 		task{
 			name: "input.css",
 			fn: func() error {
-				return createInputCSS(cssDir)
+				return createInputCSS(cssDir, *viewsGlob, *inputCSSTemplate)
 			},
 		},
 	); err != nil {
+		exitIfCancelled(ctx)
 		fatal("Setup failed: %v", err)
 	}
 
 	// Generate templ files
-	generateTempl()
+	generateTempl(ctx)
 
-	// Build CSS
-	if err := buildCSS(cssDir); err != nil {
-		fatal("Failed to build CSS: %v", err)
+	manifest, compressed, err := rebuildCSS(ctx, staticDir, *precompress)
+	if err != nil {
+		exitIfCancelled(ctx)
+		fatal("%v", err)
 	}
 
 	fmt.Println("\n✅ Setup complete!")
@@ -87,18 +163,31 @@ func main() {
 	fmt.Printf("  - %s/daisyui.mjs\n", cssDir)
 	fmt.Printf("  - %s/daisyui-theme.mjs\n", cssDir)
 	fmt.Printf("  - %s/input.css\n", cssDir)
-	fmt.Printf("  - %s/output.css\n", cssDir)
-	fmt.Printf("  - %s/datastar.js\n", jsDir)
+	fmt.Printf("  - %s/%s\n", staticDir, assets.ManifestFile)
+	for _, name := range assets.Required {
+		fmt.Printf("  - %s/%s\n", staticDir, manifest.Resolve(name))
+	}
+	for _, path := range compressed {
+		fmt.Printf("  - %s\n", path)
+	}
+	fmt.Println("\nDatastar integrity (paste into the <script> tag in your layout):")
+	fmt.Printf("  integrity=\"%s\" crossorigin=\"anonymous\"\n", datastarSRI)
 	fmt.Println("\nNext steps:")
 	fmt.Println("  make build   - Build the server")
 	fmt.Println("  make run     - Build and run the server")
 	fmt.Println("  make dev     - Run in development mode with watchers")
+
+	if *watch {
+		if err := watchCSS(ctx, cssDir); err != nil {
+			fatal("CSS watcher failed: %v", err)
+		}
+	}
 }
 
-func downloadDeps() error {
+func downloadDeps(ctx context.Context) error {
 	fmt.Println("  📦 Downloading Go dependencies...")
 
-	cmd := exec.Command("go", "mod", "tidy")
+	cmd := exec.CommandContext(ctx, "go", "mod", "tidy")
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
 	if err := cmd.Run(); err != nil {
@@ -108,35 +197,64 @@ func downloadDeps() error {
 	return nil
 }
 
-func downloadTailwind(cssDir string) error {
+func downloadTailwind(ctx context.Context, cssDir string) error {
 	fmt.Println("  📦 Downloading Tailwind CSS (latest)...")
 
 	filename := buildTailwindFilename()
 	url := fmt.Sprintf("%s/%s", tailwindBaseURL, filename)
 	destPath := filepath.Join(cssDir, "tailwindcss")
 
-	if err := downloadFile(url, destPath); err != nil {
+	if err := downloadFile(ctx, url, destPath); err != nil {
 		return err
 	}
 
 	if err := os.Chmod(destPath, 0755); err != nil {
 		return err
 	}
+	if err := checkTailwind(ctx, cssDir, filename); err != nil {
+		return err
+	}
 
 	fmt.Println("  ✅ Tailwind CSS downloaded")
 	return nil
 }
 
-func downloadDaisyUI(cssDir string) error {
+// tailwindCheckTimeout bounds the post-download `tailwindcss --help` run.
+const tailwindCheckTimeout = 10 * time.Second
+
+// checkTailwind runs the downloaded binary once, so a build for the wrong
+// platform or a corrupt download fails here with a clear message rather
+// than later as a confusing CSS build error.
+func checkTailwind(ctx context.Context, cssDir, filename string) error {
+	ctx, cancel := context.WithTimeout(ctx, tailwindCheckTimeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, "./tailwindcss", "--help")
+	cmd.Dir = cssDir
+	out, err := cmd.CombinedOutput()
+	if err == nil {
+		return nil
+	}
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		err = fmt.Errorf("no exit after %s", tailwindCheckTimeout)
+	}
+	msg := fmt.Sprintf("downloaded %s does not run on %s/%s: %v", filename, runtime.GOOS, runtime.GOARCH, err)
+	if out := strings.TrimSpace(string(out)); out != "" {
+		msg += "\n" + out
+	}
+	return errors.New(msg)
+}
+
+func downloadDaisyUI(ctx context.Context, cssDir string) error {
 	fmt.Println("  📦 Downloading DaisyUI (latest)...")
 
-	if err := runParallel(
+	if err := runParallelN(maxConcurrentDownloads,
 		task{
 			name: "daisyui.mjs",
 			fn: func() error {
 				url := fmt.Sprintf("%s/daisyui.mjs", daisyUIBaseURL)
 				destPath := filepath.Join(cssDir, "daisyui.mjs")
-				return downloadFile(url, destPath)
+				return downloadFile(ctx, url, destPath)
 			},
 		},
 		task{
@@ -144,7 +262,7 @@ func downloadDaisyUI(cssDir string) error {
 			fn: func() error {
 				url := fmt.Sprintf("%s/daisyui-theme.mjs", daisyUIBaseURL)
 				destPath := filepath.Join(cssDir, "daisyui-theme.mjs")
-				return downloadFile(url, destPath)
+				return downloadFile(ctx, url, destPath)
 			},
 		},
 	); err != nil {
@@ -155,39 +273,153 @@ func downloadDaisyUI(cssDir string) error {
 	return nil
 }
 
-func downloadDatastar(jsDir string) error {
+func downloadDatastar(ctx context.Context, jsDir string) (string, error) {
 	fmt.Println("  📦 Downloading Datastar v" + datastarVersion + "...")
 
 	destPath := filepath.Join(jsDir, "datastar.js")
-	if err := downloadFile(datastarURL, destPath); err != nil {
-		return err
+	if err := downloadFile(ctx, datastarURL, destPath); err != nil {
+		return "", err
+	}
+
+	sri, err := sriHash(destPath)
+	if err != nil {
+		return "", err
 	}
 
 	fmt.Println("  ✅ Datastar v" + datastarVersion + " downloaded")
+	return sri, nil
+}
+
+// sriHash returns the Subresource Integrity value (sha384, base64) for the
+// file at path, suitable for a <script integrity="..."> attribute.
+func sriHash(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha512.New384()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return "sha384-" + base64.StdEncoding.EncodeToString(h.Sum(nil)), nil
+}
+
+// themeKeyRe matches a theme definition in the bundled DaisyUI sources,
+// e.g. `cupcake:{"color-scheme":"light"` or `"dark": { "color-scheme"`.
+var themeKeyRe = regexp.MustCompile(`(?:^|[{,\s])["']?([a-z][a-z0-9-]*)["']?\s*:\s*\{\s*["']?color-scheme["']?\s*:`)
+
+// themeSelectorRe matches generated selectors such as [data-theme=dark].
+var themeSelectorRe = regexp.MustCompile(`\[data-theme=["']?([a-z][a-z0-9-]*)["']?\]`)
+
+// listThemes prints the theme names found in the downloaded DaisyUI files,
+// one per line and sorted, for use in input.css or the theme picker.
+func listThemes(cssDir string) error {
+	seen := make(map[string]bool)
+	var read int
+	for _, name := range []string{"daisyui-theme.mjs", "daisyui.mjs"} {
+		data, err := os.ReadFile(filepath.Join(cssDir, name))
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		read++
+		for _, re := range []*regexp.Regexp{themeKeyRe, themeSelectorRe} {
+			for _, m := range re.FindAllSubmatch(data, -1) {
+				seen[string(m[1])] = true
+			}
+		}
+	}
+
+	if read == 0 {
+		return fmt.Errorf("no DaisyUI files in %s; run the installer first", cssDir)
+	}
+	if len(seen) == 0 {
+		return errors.New("no themes found; the DaisyUI file format may have changed")
+	}
+
+	themes := make([]string, 0, len(seen))
+	for name := range seen {
+		themes = append(themes, name)
+	}
+	slices.Sort(themes)
+	for _, name := range themes {
+		fmt.Println(name)
+	}
 	return nil
 }
 
-func createInputCSS(cssDir string) error {
-	content := `@import "tailwindcss";
+//go:embed input.css.tmpl
+var defaultInputCSS string
+
+// inputCSSData is passed to the input.css template. Paths are relative to
+// the css directory, as Tailwind resolves them from input.css.
+type inputCSSData struct {
+	ViewsGlob       string
+	PluginPath      string
+	ThemePluginPath string
+}
+
+// createInputCSS renders input.css from templatePath, or from the built-in
+// template when it is empty. viewsGlob is relative to the project root.
+func createInputCSS(cssDir, viewsGlob, templatePath string) error {
+	text := defaultInputCSS
+	if templatePath != "" {
+		b, err := os.ReadFile(templatePath)
+		if err != nil {
+			return err
+		}
+		text = string(b)
+	}
 
-@source "../../internal/views/**/*.templ";
-@source not "./tailwindcss";
-@source not "./daisyui{,*}.mjs";
+	tmpl, err := template.New("input.css").Option("missingkey=error").Parse(text)
+	if err != nil {
+		return err
+	}
 
-@plugin "./daisyui.mjs";`
+	glob, err := relPath(cssDir, viewsGlob)
+	if err != nil {
+		return fmt.Errorf("views glob: %w", err)
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, inputCSSData{
+		ViewsGlob:       filepath.ToSlash(glob),
+		PluginPath:      "./daisyui.mjs",
+		ThemePluginPath: "./daisyui-theme.mjs",
+	}); err != nil {
+		return err
+	}
 
 	destPath := filepath.Join(cssDir, "input.css")
-	if err := os.WriteFile(destPath, []byte(content), 0644); err != nil {
+	if err := os.WriteFile(destPath, buf.Bytes(), 0644); err != nil {
 		return err
 	}
 	fmt.Println("  ✅ Created input.css")
 	return nil
 }
 
-func generateTempl() {
+// relPath returns target relative to base, resolving both from the working
+// directory first so either may be absolute.
+func relPath(base, target string) (string, error) {
+	absBase, err := filepath.Abs(base)
+	if err != nil {
+		return "", err
+	}
+	absTarget, err := filepath.Abs(target)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Rel(absBase, absTarget)
+}
+
+func generateTempl(ctx context.Context) {
 	fmt.Println("  🔨 Generating templ files...")
 
-	cmd := exec.Command("go", "tool", "templ", "generate")
+	cmd := exec.CommandContext(ctx, "go", "tool", "templ", "generate")
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
 	if err := cmd.Run(); err != nil {
@@ -197,11 +429,11 @@ func generateTempl() {
 	fmt.Println("  ✅ templ files generated")
 }
 
-func buildCSS(cssDir string) error {
+func buildCSS(ctx context.Context, cssDir string) error {
 	fmt.Println("  🔨 Building CSS...")
 
 	// Run from cssDir, so use relative paths
-	cmd := exec.Command("./tailwindcss", "-i", "input.css", "-o", "output.css", "--minify")
+	cmd := exec.CommandContext(ctx, "./tailwindcss", "-i", "input.css", "-o", "output.css", "--minify")
 	cmd.Dir = cssDir
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
@@ -212,8 +444,156 @@ func buildCSS(cssDir string) error {
 	return nil
 }
 
-func downloadFile(url, destPath string) error {
-	resp, err := http.Get(url)
+// rebuildCSS builds, fingerprints and optionally precompresses the CSS in
+// staticDir/css. It returns the asset manifest and any compressed copies.
+func rebuildCSS(ctx context.Context, staticDir string, precompress bool) (assets.Manifest, []string, error) {
+	if err := buildCSS(ctx, filepath.Join(staticDir, "css")); err != nil {
+		return nil, nil, fmt.Errorf("failed to build CSS: %w", err)
+	}
+
+	manifest, err := fingerprintCSS(staticDir)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to fingerprint CSS: %w", err)
+	}
+
+	var compressed []string
+	if precompress {
+		compressed, err = precompressFile(filepath.Join(staticDir, manifest.Resolve("css/output.css")))
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to precompress CSS: %w", err)
+		}
+	}
+	return manifest, compressed, nil
+}
+
+// fingerprintCSS renames css/output.css to css/output.<hash>.css and records
+// the mapping in the asset manifest so the server can emit cache-busting
+// URLs. It returns the manifest it wrote.
+func fingerprintCSS(staticDir string) (assets.Manifest, error) {
+	fmt.Println("  🔨 Fingerprinting CSS...")
+
+	cssDir := filepath.Join(staticDir, "css")
+	src := filepath.Join(cssDir, "output.css")
+
+	data, err := os.ReadFile(src)
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(data)
+	name := "output." + hex.EncodeToString(sum[:5]) + ".css"
+
+	// Drop fingerprints (and their precompressed copies) from earlier builds
+	// so they don't pile up.
+	old, err := filepath.Glob(filepath.Join(cssDir, "output.*.css"))
+	if err != nil {
+		return nil, err
+	}
+	oldCompressed, err := filepath.Glob(filepath.Join(cssDir, "output.*.css.*"))
+	if err != nil {
+		return nil, err
+	}
+	old = append(old, oldCompressed...)
+	for _, f := range old {
+		if err := os.Remove(f); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := os.Rename(src, filepath.Join(cssDir, name)); err != nil {
+		return nil, err
+	}
+
+	manifest := assets.Manifest{
+		"css/output.css": "css/" + name,
+	}
+	out, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(filepath.Join(staticDir, assets.ManifestFile), out, 0644); err != nil {
+		return nil, err
+	}
+
+	fmt.Println("  ✅ CSS fingerprinted as " + name)
+	return manifest, nil
+}
+
+// precompressFile writes Brotli and gzip copies of path next to it, at the
+// highest compression levels since this runs once per build. The static
+// handler serves them to clients that accept those encodings. It returns
+// the paths it wrote.
+func precompressFile(path string) ([]string, error) {
+	fmt.Println("  🔨 Precompressing CSS...")
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	encoders := []struct {
+		ext string
+		new func(io.Writer) io.WriteCloser
+	}{
+		{".br", func(w io.Writer) io.WriteCloser { return brotli.NewWriterLevel(w, brotli.BestCompression) }},
+		{".gz", func(w io.Writer) io.WriteCloser {
+			gz, _ := gzip.NewWriterLevel(w, gzip.BestCompression)
+			return gz
+		}},
+	}
+
+	var written []string
+	for _, enc := range encoders {
+		var buf bytes.Buffer
+		zw := enc.new(&buf)
+		if _, err := zw.Write(data); err != nil {
+			return nil, err
+		}
+		if err := zw.Close(); err != nil {
+			return nil, err
+		}
+		dest := path + enc.ext
+		if err := os.WriteFile(dest, buf.Bytes(), 0644); err != nil {
+			return nil, err
+		}
+		fmt.Printf("  ✅ %s: %d → %d bytes\n", filepath.Base(dest), len(data), buf.Len())
+		written = append(written, dest)
+	}
+	return written, nil
+}
+
+// watchCSS runs tailwindcss in watch mode in the foreground until ctx is
+// cancelled (SIGINT or SIGTERM), at which point the subprocess is killed.
+func watchCSS(ctx context.Context, cssDir string) error {
+	// The watcher writes the unhashed output.css, so drop the manifest to
+	// have the server link to it instead of a stale fingerprint.
+	if err := os.Remove(filepath.Join(cssDir, "..", assets.ManifestFile)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	fmt.Println("\n👀 Watching for CSS changes (Ctrl-C to stop)...")
+
+	cmd := exec.CommandContext(ctx, "./tailwindcss", "-i", "input.css", "-o", "output.css", "--watch")
+	cmd.Dir = cssDir
+	// Tailwind stops watching when stdin closes, so hand it ours.
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil && ctx.Err() == nil {
+		return err
+	}
+	fmt.Println("  👋 CSS watcher stopped")
+	return nil
+}
+
+// downloadFile fetches url into destPath. It writes to a temporary file in
+// the same directory and renames it into place only once complete, so a
+// failed or cancelled download never leaves a partial file at destPath.
+func downloadFile(ctx context.Context, url, destPath string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
 		return err
 	}
@@ -223,14 +603,25 @@ func downloadFile(url, destPath string) error {
 		return fmt.Errorf("download returned status %d for %s", resp.StatusCode, url)
 	}
 
-	out, err := os.Create(destPath)
+	out, err := os.CreateTemp(filepath.Dir(destPath), filepath.Base(destPath)+".*.tmp")
 	if err != nil {
 		return err
 	}
-	defer out.Close()
+	defer os.Remove(out.Name()) // no-op after a successful rename
 
-	_, err = io.Copy(out, resp.Body)
-	return err
+	if _, err := io.Copy(out, resp.Body); err != nil {
+		out.Close()
+		return err
+	}
+	// CreateTemp uses 0600; match what os.Create would have produced.
+	if err := out.Chmod(0644); err != nil {
+		out.Close()
+		return err
+	}
+	if err := out.Close(); err != nil {
+		return err
+	}
+	return os.Rename(out.Name(), destPath)
 }
 
 func buildTailwindFilename() string {
@@ -269,6 +660,36 @@ func isMusl() bool {
 	return strings.Contains(string(output), "musl")
 }
 
+// exitIfCancelled exits quietly when the installer was interrupted, so the
+// resulting context errors aren't reported as failures.
+func exitIfCancelled(ctx context.Context) {
+	if ctx.Err() != nil {
+		fmt.Fprintln(os.Stderr, "\n🛑 Installation cancelled")
+		os.Exit(130)
+	}
+}
+
+// installDir returns the static dir for a plain install from the
+// positional args. A first argument that is not an existing directory must
+// look like a path, so a mistyped command such as "updat" is reported
+// rather than installed into; "static" and "./name" are always accepted.
+func installDir(args []string) (string, error) {
+	if len(args) == 0 {
+		return "static", nil
+	}
+	dir := args[0]
+	info, err := os.Stat(dir)
+	isDir := err == nil && info.IsDir()
+	looksLikePath := dir == "static" || strings.ContainsAny(dir, "/"+string(filepath.Separator))
+	if !isDir && !looksLikePath {
+		return "", fmt.Errorf("unknown command %q (to install into a new directory, pass ./%s)", dir, dir)
+	}
+	if len(args) > 1 {
+		return "", fmt.Errorf("unexpected argument %q after static dir %q", args[1], dir)
+	}
+	return dir, nil
+}
+
 func fatal(format string, args ...any) {
 	fmt.Fprintf(os.Stderr, "❌ "+format+"\n", args...)
 	os.Exit(1)
@@ -279,15 +700,23 @@ type task struct {
 	fn   func() error
 }
 
+// runParallel runs every task concurrently and joins their errors.
 func runParallel(tasks ...task) error {
+	return runParallelN(len(tasks), tasks...)
+}
+
+// runParallelN is like runParallel but runs at most n tasks at a time.
+func runParallelN(n int, tasks ...task) error {
 	var wg sync.WaitGroup
 	errCh := make(chan error, len(tasks))
+	sem := make(chan struct{}, max(n, 1))
 
 	for _, t := range tasks {
-		t := t
 		wg.Add(1)
 		go func() {
 			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
 			if err := t.fn(); err != nil {
 				errCh <- fmt.Errorf("%s: %w", t.name, err)
 			}
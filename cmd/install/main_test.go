@@ -0,0 +1,96 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRunParallelNRespectsCap(t *testing.T) {
+	for _, limit := range []int{1, 2, 3} {
+		t.Run(fmt.Sprint(limit), func(t *testing.T) {
+			var running, peak, done atomic.Int32
+			tasks := make([]task, 10)
+			for i := range tasks {
+				tasks[i] = task{name: fmt.Sprint(i), fn: func() error {
+					n := running.Add(1)
+					for {
+						p := peak.Load()
+						if n <= p || peak.CompareAndSwap(p, n) {
+							break
+						}
+					}
+					time.Sleep(5 * time.Millisecond)
+					running.Add(-1)
+					done.Add(1)
+					return nil
+				}}
+			}
+
+			if err := runParallelN(limit, tasks...); err != nil {
+				t.Fatalf("runParallelN: %v", err)
+			}
+			if got := done.Load(); got != int32(len(tasks)) {
+				t.Fatalf("%d tasks ran, want %d", got, len(tasks))
+			}
+			if got := peak.Load(); got > int32(limit) {
+				t.Fatalf("%d tasks ran at once, cap is %d", got, limit)
+			}
+		})
+	}
+}
+
+func TestRunParallelNJoinsErrors(t *testing.T) {
+	errBoom := errors.New("boom")
+	err := runParallelN(2,
+		task{name: "ok", fn: func() error { return nil }},
+		task{name: "a", fn: func() error { return errBoom }},
+		task{name: "b", fn: func() error { return errBoom }},
+	)
+	if !errors.Is(err, errBoom) {
+		t.Fatalf("err = %v, want boom", err)
+	}
+	for _, name := range []string{"a: boom", "b: boom"} {
+		if !strings.Contains(err.Error(), name) {
+			t.Errorf("error %q lacks %q", err, name)
+		}
+	}
+}
+
+func TestInstallDir(t *testing.T) {
+	existing := t.TempDir()
+	t.Chdir(existing)
+	if err := os.Mkdir("assets", 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		args    []string
+		want    string
+		wantErr string
+	}{
+		{args: nil, want: "static"},
+		{args: []string{"static"}, want: "static"},
+		{args: []string{"assets"}, want: "assets"},
+		{args: []string{"./public"}, want: "./public"},
+		{args: []string{existing}, want: existing},
+		{args: []string{"updat", "datastar"}, wantErr: `unknown command "updat"`},
+		{args: []string{"list-theme"}, wantErr: `unknown command "list-theme"`},
+		{args: []string{"static", "extra"}, wantErr: `unexpected argument "extra"`},
+	} {
+		got, err := installDir(tc.args)
+		if tc.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+				t.Errorf("installDir(%q) error = %v, want %q", tc.args, err, tc.wantErr)
+			}
+			continue
+		}
+		if err != nil || got != tc.want {
+			t.Errorf("installDir(%q) = %q, %v; want %q", tc.args, got, err, tc.want)
+		}
+	}
+}
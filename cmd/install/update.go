@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// updatable are the assets "install update" can refresh on their own.
+var updatable = []string{"datastar", "tailwind", "daisyui"}
+
+// update downloads only the named asset into staticDir and, for the ones
+// that feed into it, rebuilds the CSS. It expects a completed install, since
+// the rebuild needs input.css and the other CSS tools in place.
+func update(ctx context.Context, asset, staticDir string, precompress bool) error {
+	if !slices.Contains(updatable, asset) {
+		return fmt.Errorf("unknown asset %q; expected one of %s", asset, strings.Join(updatable, ", "))
+	}
+
+	cssDir := filepath.Join(staticDir, "css")
+	jsDir := filepath.Join(staticDir, "js")
+
+	switch asset {
+	case "datastar":
+		if err := os.MkdirAll(jsDir, 0755); err != nil {
+			return err
+		}
+		sri, err := downloadDatastar(ctx, jsDir)
+		if err != nil {
+			return err
+		}
+		if info, err := os.Stat(filepath.Join(jsDir, "datastar.js")); err != nil {
+			return err
+		} else if info.Size() == 0 {
+			return errors.New("downloaded datastar.js is empty")
+		}
+		fmt.Println("\nDatastar integrity (paste into the <script> tag in your layout):")
+		fmt.Printf("  integrity=\"%s\" crossorigin=\"anonymous\"\n", sri)
+		return nil
+	case "tailwind":
+		if err := requireInstalled(cssDir, "input.css", "daisyui.mjs"); err != nil {
+			return err
+		}
+		if err := downloadTailwind(ctx, cssDir); err != nil {
+			return err
+		}
+	case "daisyui":
+		if err := requireInstalled(cssDir, "input.css", "tailwindcss"); err != nil {
+			return err
+		}
+		if err := downloadDaisyUI(ctx, cssDir); err != nil {
+			return err
+		}
+	}
+
+	// Building with the new tool or plugin is what verifies it.
+	_, compressed, err := rebuildCSS(ctx, staticDir, precompress)
+	if err != nil {
+		return err
+	}
+	for _, path := range compressed {
+		fmt.Printf("  - %s\n", path)
+	}
+	return nil
+}
+
+// requireInstalled checks that the named files exist in dir, pointing at the
+// full installer if not.
+func requireInstalled(dir string, names ...string) error {
+	for _, name := range names {
+		if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				return fmt.Errorf("%s not found in %s; run the full installer first", name, dir)
+			}
+			return err
+		}
+	}
+	return nil
+}
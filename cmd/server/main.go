@@ -2,70 +2,358 @@ package main
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
+	"expvar"
+	"flag"
+	"io"
 	"log/slog"
+	"net"
 	"net/http"
+	"net/http/pprof"
 	"os"
 	"os/signal"
+	"strconv"
+	"sync/atomic"
 	"syscall"
 	"time"
 
+	"github.com/ankit-lilly/go-datastar-daisyui-template/internal/assets"
+	"github.com/ankit-lilly/go-datastar-daisyui-template/internal/audit"
+	"github.com/ankit-lilly/go-datastar-daisyui-template/internal/broadcast"
+	"github.com/ankit-lilly/go-datastar-daisyui-template/internal/buildinfo"
 	"github.com/ankit-lilly/go-datastar-daisyui-template/internal/config"
+	"github.com/ankit-lilly/go-datastar-daisyui-template/internal/features"
 	"github.com/ankit-lilly/go-datastar-daisyui-template/internal/handlers"
 	"github.com/ankit-lilly/go-datastar-daisyui-template/internal/jobs"
+	"github.com/ankit-lilly/go-datastar-daisyui-template/internal/lifecycle"
+	"github.com/ankit-lilly/go-datastar-daisyui-template/internal/middleware"
+	"github.com/ankit-lilly/go-datastar-daisyui-template/internal/session"
+	"github.com/ankit-lilly/go-datastar-daisyui-template/internal/sseutil"
+	"github.com/ankit-lilly/go-datastar-daisyui-template/internal/store"
+	"github.com/ankit-lilly/go-datastar-daisyui-template/internal/telemetry"
+	"github.com/ankit-lilly/go-datastar-daisyui-template/internal/util"
+	"github.com/ankit-lilly/go-datastar-daisyui-template/internal/views"
 )
 
 func main() {
+	staticDirCheck := flag.Bool("static-dir-check", false, "refuse to start when required static assets are missing")
+	pprofFlag := flag.Bool("pprof", false, "mount net/http/pprof at /debug/pprof/ (also ENABLE_PPROF=1)")
+	flag.Parse()
+
+	// logLevel follows LOG_LEVEL across config reloads.
+	var logLevel slog.LevelVar
 	logger := slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{
-		Level: slog.LevelInfo,
+		Level: &logLevel,
 	}))
 	slog.SetDefault(logger)
 
-	cfg := config.Load()
+	cfg, err := config.Load()
+	if err != nil {
+		logger.Error("failed to load config", "error", err)
+		os.Exit(1)
+	}
+	if err := cfg.Validate(); err != nil {
+		logger.Error("invalid config", "error", err)
+		os.Exit(1)
+	}
+	pprofOn := *pprofFlag || cfg.EnablePprof
+	if err := checkPprof(pprofOn, cfg); err != nil {
+		logger.Error("refusing to start", "error", err)
+		os.Exit(1)
+	}
+	config.Set(cfg)
+	logLevel.Set(cfg.LogLevel)
+
+	bi := buildinfo.Get()
+	logger.Info("build info",
+		"version", bi.Version,
+		"commit", bi.Commit,
+		"build_time", bi.BuildTime,
+		"go_version", bi.GoVersion,
+		"datastar_version", bi.DatastarVersion,
+	)
+
+	manifest, err := assets.LoadManifest(cfg.StaticDir)
+	if err != nil {
+		logger.Warn("failed to load asset manifest", "error", err)
+	}
+	views.SetAssetManifest(manifest)
+	views.SetAssetPrefix(cfg.StaticPrefix)
+
+	missing := assets.Missing(cfg.StaticDir, manifest)
+	if len(missing) > 0 {
+		if *staticDirCheck {
+			logger.Error("required static assets missing; run 'go run ./cmd/install'", "missing", missing)
+			os.Exit(1)
+		}
+		logger.Warn("required static assets missing, serving the setup page; run 'go run ./cmd/install'", "missing", missing)
+		// The fallback assets are not fingerprinted.
+		views.SetAssetManifest(assets.Manifest{})
+	}
+
+	lc := lifecycle.New(logger)
+
+	// Registered first so it runs last and flushes spans from the other hooks.
+	shutdownTracing, tracing, err := telemetry.Setup(context.Background())
+	if err != nil {
+		logger.Error("failed to set up tracing", "error", err)
+		os.Exit(1)
+	}
+	if tracing {
+		logger.Info("tracing enabled")
+	}
+	lc.OnShutdown("telemetry", shutdownTracing)
+
+	// Opened before the job hub so it closes after the hub has stopped.
+	var auditLog *audit.Logger
+	if cfg.AuditLogPath != "" {
+		auditLog, err = audit.Open(cfg.AuditLogPath)
+		if err != nil {
+			logger.Error("failed to open audit log", "path", cfg.AuditLogPath, "error", err)
+			os.Exit(1)
+		}
+		audit.SetDefault(auditLog)
+		lc.OnShutdown("audit log", func(context.Context) error {
+			audit.SetDefault(nil)
+			return auditLog.Close()
+		})
+	}
 
-	jobHub := jobs.NewHub(logger)
+	// Validate has already checked the policy name.
+	queuePolicy, _ := jobs.ParseQueuePolicy(cfg.JobQueuePolicy)
+	hubOpts := []jobs.Option{
+		jobs.WithWorkers(cfg.JobWorkers),
+		jobs.WithIDPrefix(cfg.JobIDPrefix),
+		jobs.WithQueuePolicy(queuePolicy, cfg.JobQueueTimeout),
+		jobs.WithMaxJobsPerOwner(cfg.JobMaxPerClient),
+		jobs.WithUpdateBuffer(cfg.JobUpdateBuffer),
+		jobs.WithRetention(cfg.JobRetention),
+		jobs.WithProgressInterval(cfg.JobProgressInterval),
+		jobs.WithProgressLogStep(cfg.JobProgressLogStep),
+	}
+	if cfg.JobStorePath != "" {
+		store, err := jobs.OpenSQLiteStore(cfg.JobStorePath)
+		if err != nil {
+			logger.Error("failed to open job store", "path", cfg.JobStorePath, "error", err)
+			os.Exit(1)
+		}
+		hubOpts = append(hubOpts, jobs.WithStore(store))
+		if closer, ok := store.(io.Closer); ok {
+			lc.OnShutdown("job store", func(context.Context) error {
+				return closer.Close()
+			})
+		}
+	}
+
+	jobHub := jobs.NewHub(logger, hubOpts...)
 	go jobHub.Run()
+	// Running jobs get JOB_SHUTDOWN_GRACE of the shutdown budget to finish.
+	// Cancelling the ones that don't is expected, so only running out of
+	// the whole budget is reported.
+	lc.OnShutdown("job hub", func(ctx context.Context) error {
+		grace, cancel := context.WithTimeout(ctx, cfg.JobShutdownGrace)
+		defer cancel()
+		jobHub.StopGraceful(grace)
+		return ctx.Err()
+	})
+
+	secret := cfg.SessionSecret
+	if secret == "" {
+		logger.Warn("SESSION_SECRET not set, using a random secret; sessions will not survive restarts")
+		secret = util.GenerateID()
+	}
+	sessions := session.NewManager([]byte(secret), cfg.SessionTTL)
+	go sessions.Run()
+	lc.OnShutdown("sessions", func(context.Context) error {
+		sessions.Stop()
+		return nil
+	})
 
 	mux := http.NewServeMux()
-	h := handlers.New(logger, jobHub)
+	broadcaster := broadcast.NewHub(logger)
+	// Per-session counters expire with the sessions that own them.
+	counters := store.New[string, *atomic.Int64](cfg.SessionTTL)
+	go counters.Run(time.Minute)
+	lc.OnShutdown("counter store", func(context.Context) error {
+		counters.Stop()
+		return nil
+	})
 
-	mux.Handle("GET /static/", http.StripPrefix("/static/", http.FileServer(http.Dir("static"))))
+	h := handlers.New(logger, jobHub, broadcaster, counters)
 
-	mux.HandleFunc("GET /", h.Index)
+	// Precompressed .br/.gz siblings are preferred; other text assets are
+	// gzipped on the fly. Caching is off in development and aggressive in
+	// production.
+	static := assets.CacheControl(assets.FileServer(cfg.StaticDir), cfg.Production(), manifest)
+	if len(missing) > 0 {
+		// Without the installer's output the app cannot work, so "/"
+		// explains how to fix that and the assets are embedded stand-ins.
+		static = assets.FallbackServer()
+		mux.Handle("GET /", assets.SetupPage(cfg.StaticDir, views.AssetPath("css/output.css"), missing))
+	} else {
+		mux.HandleFunc("GET /", h.Index)
+	}
+	mountStatic(mux, cfg, static)
+	mux.Handle("GET /favicon.ico", assets.Favicon(cfg.FaviconPath))
 
-	mux.HandleFunc("GET /api/counter", h.Counter)
-	mux.HandleFunc("POST /api/increment", h.Increment)
-	mux.HandleFunc("POST /api/job/start", h.StartJob)
+	// Handlers that answer and return get a hard deadline. The SSE streams
+	// (chat messages, the clock and job progress) stay open as long as the client does,
+	// so they are left without one.
+	timeout := middleware.Timeout(cfg.RequestTimeout)
+	api := func(pattern string, handler http.HandlerFunc) {
+		mux.Handle(pattern, timeout(handler))
+	}
+	// Routes that only answer with SSE turn away requests that did not ask
+	// for a stream, such as a plain GET from a browser tab or curl, with a
+	// 400 before the handler runs.
+	sse := func(pattern string, handler http.HandlerFunc) {
+		mux.Handle(pattern, timeout(sseutil.Require(handler)))
+	}
+
+	api("GET /healthz", h.Healthz)
+	api("GET /api/version", h.Version)
+	sse("POST /api/theme", h.SetTheme)
+	if !cfg.Production() {
+		sse("POST /api/debug/signals", h.DebugSignals)
+	}
+
+	demoRoutes(mux, h, api, sse)
+
+	protect := func(next http.Handler) http.Handler { return next }
+	if cfg.BasicAuthUser != "" {
+		protect = middleware.BasicAuth(cfg.BasicAuthRealm, cfg.BasicAuthUser, cfg.BasicAuthPassword)
+	} else {
+		logger.Warn("BASIC_AUTH_USER not set; admin routes such as /api/admin/ and /debug/vars are disabled")
+	}
+	admin := adminRouter(mux, cfg)
+
+	if features.Enabled(features.Jobs) {
+		mux.Handle("POST /api/job/start", protect(http.HandlerFunc(h.StartJob)))
+		mux.Handle("POST /api/job/run", protect(http.HandlerFunc(h.RunJob)))
+		mux.Handle("GET /api/jobs", protect(timeout(http.HandlerFunc(h.JobsList))))
+		mux.Handle("GET /api/jobs.json", protect(timeout(http.HandlerFunc(h.JobsListJSON))))
+		// Results can be large, so the download has no request deadline.
+		mux.Handle("GET /api/job/{id}/result", protect(http.HandlerFunc(h.JobResult)))
+		mux.Handle("POST /api/job/{id}/rerun", protect(timeout(http.HandlerFunc(h.RerunJob))))
+		mux.Handle("POST /api/job/{id}/pause", protect(timeout(http.HandlerFunc(h.PauseJob))))
+		mux.Handle("POST /api/job/{id}/resume", protect(timeout(http.HandlerFunc(h.ResumeJob))))
+		// Cancelling everyone's jobs is for admins only.
+		admin("POST /api/jobs/cancel-all", timeout(http.HandlerFunc(h.CancelAllJobs)))
+	}
+	admin("GET /debug/vars", expvar.Handler())
+	maintenance := middleware.NewMaintenanceMode()
+	admin("POST /api/admin/maintenance", setMaintenance(logger, maintenance))
+
+	// Long-lived SSE streams only end when their request context does, so
+	// cancel every request context once shutdown begins. The cause tells
+	// handlers it wasn't the client that left.
+	baseCtx, cancelRequests := context.WithCancelCause(context.Background())
+
+	sec := cfg.Security
+	csrfSecret := sec.CSRFSecret
+	if csrfSecret == "" {
+		logger.Warn("CSRF_SECRET not set, using a random secret; open pages need a reload after restarts")
+		csrfSecret = util.GenerateID()
+	}
+
+	// Middleware, innermost first.
+	var handler http.Handler = middleware.SSEMetrics(middleware.MatchRoute(mux))
+	handler = maintenance.Middleware("/healthz", "/api/admin/", "/favicon.ico", cfg.StaticPrefix)(handler)
+	handler = middleware.Theme(func() []string { return config.Current().Themes })(handler)
+	handler = middleware.Nav(func() []config.NavItem { return config.Current().NavItems })(handler)
+	handler = middleware.CSP(handler)
+	handler = middleware.CSRF([]byte(csrfSecret))(handler)
+	handler = sessions.Middleware(handler)
+	handler = middleware.CORS(sec.AllowedOrigins)(handler)
+	// Routes needing bigger bodies, such as uploads, can be given their own
+	// limit: middleware.BodyLimit{Prefix: "/api/upload", Limit: 32 << 20}.
+	handler = middleware.MaxBody(sec.MaxBodyBytes)(handler)
+	// Always installed, so a reload can turn RATE_LIMIT on or off.
+	limiter := middleware.NewRateLimiter(sec.RateLimit, sec.RateBurst)
+	go limiter.Run(time.Minute)
+	lc.OnShutdown("rate limiter", func(context.Context) error {
+		limiter.Stop()
+		return nil
+	})
+	handler = limiter.Middleware(handler)
+	// Redirect before CSRF and the rest, so a POST to a path with a stray
+	// slash is answered with a redirect rather than a 403.
+	handler = middleware.CleanPath(cfg.StaticPrefix)(handler)
+	var inFlight atomic.Int64
+	handler = logRequests(logger, &inFlight, cfg.StaticPrefix, handler)
+	handler = middleware.RealIP(sec.TrustedProxies)(handler)
+	handler = middleware.ServerTiming(handler)
+	handler = middleware.Tracing(handler)
+	if pprofOn {
+		logger.Warn("pprof enabled at /debug/pprof/; do not expose this publicly")
+		handler = withPprof(handler, protect)
+	}
 
 	server := &http.Server{
 		Addr:         cfg.Addr,
-		Handler:      logRequests(logger, mux),
+		Handler:      handler,
 		ReadTimeout:  15 * time.Second,
 		WriteTimeout: 0,
 		IdleTimeout:  60 * time.Second,
+		BaseContext:  func(net.Listener) context.Context { return baseCtx },
 	}
+	server.RegisterOnShutdown(func() { cancelRequests(lifecycle.ErrShuttingDown) })
+	lc.OnShutdown("http server", server.Shutdown)
 
 	go func() {
-		logger.Info("server starting", "addr", cfg.Addr)
+		// One record with everything that shapes behaviour, for telling why
+		// two deployments act differently. Secrets are redacted.
+		logger.Info("server starting",
+			"addr", cfg.Addr,
+			"config", cfg,
+			"pprof", pprofOn,
+			"tracing", tracing,
+			"metrics", "/debug/vars",
+			"setup_page", len(missing) > 0,
+		)
 		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
 			logger.Error("server error", "error", err)
 			os.Exit(1)
 		}
 	}()
 
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	go func() {
+		for range hup {
+			reloadConfig(logger, &logLevel, limiter)
+			// Pick up a new file after logrotate has moved the old one.
+			if auditLog != nil {
+				if err := auditLog.Reopen(); err != nil {
+					logger.Error("failed to reopen audit log", "error", err)
+				}
+			}
+		}
+	}()
+
+	usr1 := make(chan os.Signal, 1)
+	signal.Notify(usr1, syscall.SIGUSR1)
+	go func() {
+		for range usr1 {
+			logger.Warn("maintenance mode toggled by SIGUSR1", "enabled", maintenance.Toggle())
+		}
+	}()
+
 	// Wait for interrupt signal
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 	<-quit
 
-	logger.Info("shutting down server...")
+	logger.Info("shutting down server...", "requests_in_flight", inFlight.Load(), "jobs_running", jobHub.Running())
 
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
-	jobHub.Stop()
-
-	if err := server.Shutdown(ctx); err != nil {
+	err = lc.Shutdown(ctx)
+	// Anything still counted here outlived the shutdown, e.g. a stuck stream.
+	logger.Info("shutdown finished", "requests_in_flight", inFlight.Load(), "jobs_running", jobHub.Running())
+	if err != nil {
 		logger.Error("server forced to shutdown", "error", err)
 		os.Exit(1)
 	}
@@ -73,15 +361,169 @@ func main() {
 	logger.Info("server stopped gracefully")
 }
 
-func logRequests(logger *slog.Logger, next http.Handler) http.Handler {
+// reloadConfig re-reads the configuration on SIGHUP. Only reloadable fields
+// take effect; changes to the others are logged and ignored.
+func reloadConfig(logger *slog.Logger, logLevel *slog.LevelVar, limiter *middleware.RateLimiter) {
+	cfg, ignored, err := config.Reload()
+	if err != nil {
+		logger.Error("config reload failed, keeping current config", "error", err)
+		return
+	}
+	logLevel.Set(cfg.LogLevel)
+	limiter.SetLimits(cfg.Security.RateLimit, cfg.Security.RateBurst)
+	if len(ignored) > 0 {
+		logger.Warn("config fields changed but require a restart", "fields", ignored)
+	}
+	logger.Info("config reloaded",
+		"log_level", cfg.LogLevel,
+		"render_timeout", cfg.RenderTimeout,
+		"themes", cfg.Themes,
+		"rate_limit", cfg.Security.RateLimit,
+		"rate_burst", cfg.Security.RateBurst,
+	)
+}
+
+// quietPaths are requested by browsers on their own and left out of the
+// access log.
+var quietPaths = map[string]bool{
+	"/favicon.ico": true,
+}
+
+// logRequests logs each request once it finishes and counts the requests
+// in flight in inFlight. Each line carries the matched route and its group
+// (see routeGroup), so e.g. job API calls can be told from page loads.
+func logRequests(logger *slog.Logger, inFlight *atomic.Int64, staticPrefix string, next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		start := time.Now()
+		inFlight.Add(1)
+		defer inFlight.Add(-1)
+		r, route := middleware.WithRouteInfo(r)
 		next.ServeHTTP(w, r)
+		if quietPaths[r.URL.Path] {
+			return
+		}
 		logger.Info("request",
 			"method", r.Method,
 			"path", r.URL.Path,
+			"route", route.Pattern,
+			"route_group", routeGroup(route, staticPrefix),
 			"duration", time.Since(start),
 			"remote", r.RemoteAddr,
 		)
 	})
 }
+
+// routeGroup sorts a handled request into page, static, sse (answered with
+// an event stream, long-lived or not) or api (everything else). Requests
+// that never reached a handler, such as 404s, redirects and requests
+// rejected by middleware, are unmatched.
+func routeGroup(route *middleware.RouteInfo, staticPrefix string) string {
+	switch {
+	case route.Pattern == "":
+		return "unmatched"
+	case route.Pattern == "GET "+staticPrefix, route.Pattern == "GET /favicon.ico":
+		return "static"
+	case route.Stream:
+		return "sse"
+	case route.Pattern == "GET /":
+		return "page"
+	default:
+		return "api"
+	}
+}
+
+// mountStatic serves static under cfg.StaticPrefix, the prefix AssetPath
+// builds URLs with.
+func mountStatic(mux *http.ServeMux, cfg *config.Config, static http.Handler) {
+	mux.Handle("GET "+cfg.StaticPrefix, http.StripPrefix(cfg.StaticPrefix, middleware.Gzip(cfg.GzipLevel)(static)))
+}
+
+// demoRoutes registers the routes of the enabled demos other than jobs,
+// which need the admin and auth wrappers set up in main. api and sse wrap
+// handlers as in main. Disabled features' routes are never registered, so
+// they 404.
+func demoRoutes(mux *http.ServeMux, h *handlers.Handlers, api, sse func(pattern string, handler http.HandlerFunc)) {
+	if features.Enabled(features.Counter) {
+		api("GET /api/counter", h.Counter)
+		sse("POST /api/increment", h.Increment)
+		sse("POST /api/increment/by", h.IncrementBy)
+		sse("GET /api/counter/optimistic", h.OptimisticCounter)
+		sse("POST /api/counter/optimistic/increment", h.OptimisticIncrement)
+	}
+	if features.Enabled(features.Wizard) {
+		sse("GET /api/wizard/step", h.WizardStep)
+		sse("POST /api/wizard/account", h.WizardAccount)
+		sse("POST /api/wizard/plan", h.WizardPlan)
+		sse("POST /api/wizard/reset", h.WizardReset)
+	}
+	if features.Enabled(features.Chat) {
+		sse("POST /api/messages", h.PostMessage)
+		mux.HandleFunc("GET /api/messages/stream", h.MessagesStream)
+	}
+	if features.Enabled(features.Clock) {
+		mux.HandleFunc("GET /api/clock", h.Clock)
+	}
+}
+
+// adminRouter returns a function that registers routes acting on every
+// client's state, such as maintenance mode, behind basic auth. Without
+// credentials configured they are not registered at all and answer 404:
+// CSRF alone doesn't stop a script that fetches the cookie first.
+func adminRouter(mux *http.ServeMux, cfg *config.Config) func(pattern string, handler http.Handler) {
+	if cfg.BasicAuthUser == "" {
+		return func(string, http.Handler) {}
+	}
+	auth := middleware.BasicAuth(cfg.BasicAuthRealm, cfg.BasicAuthUser, cfg.BasicAuthPassword)
+	return func(pattern string, handler http.Handler) {
+		mux.Handle(pattern, auth(handler))
+	}
+}
+
+// setMaintenance turns maintenance mode on or off (?enabled=true|false), or
+// toggles it without the parameter, and answers {"maintenance": bool}.
+func setMaintenance(logger *slog.Logger, m *middleware.MaintenanceMode) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var on bool
+		if v := r.URL.Query().Get("enabled"); v != "" {
+			b, err := strconv.ParseBool(v)
+			if err != nil {
+				http.Error(w, "Bad Request: invalid enabled", http.StatusBadRequest)
+				return
+			}
+			m.Set(b)
+			on = b
+		} else {
+			on = m.Toggle()
+		}
+		logger.Warn("maintenance mode set", "enabled", on, "remote", r.RemoteAddr)
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]bool{"maintenance": on})
+	})
+}
+
+// checkPprof refuses profiling without basic auth: the profile endpoints
+// expose command lines and heap contents and can keep the CPU busy.
+func checkPprof(on bool, cfg *config.Config) error {
+	if on && cfg.BasicAuthUser == "" {
+		return errors.New("pprof needs BASIC_AUTH_USER and BASIC_AUTH_PASSWORD")
+	}
+	return nil
+}
+
+// withPprof serves the net/http/pprof handlers under /debug/pprof/ and
+// everything else from next. The profiling routes skip the app's session,
+// CSRF and request-logging middleware, which would get in the way of long
+// profile downloads and the tools' POSTs, but still go through protect.
+func withPprof(next http.Handler, protect func(http.Handler) http.Handler) http.Handler {
+	debug := http.NewServeMux()
+	debug.HandleFunc("/debug/pprof/", pprof.Index)
+	debug.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	debug.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	debug.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	debug.HandleFunc("/debug/pprof/trace", pprof.Trace)
+
+	root := http.NewServeMux()
+	root.Handle("/debug/pprof/", protect(debug))
+	root.Handle("/", next)
+	return root
+}
@@ -0,0 +1,242 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ankit-lilly/go-datastar-daisyui-template/internal/assets"
+	"github.com/ankit-lilly/go-datastar-daisyui-template/internal/broadcast"
+	"github.com/ankit-lilly/go-datastar-daisyui-template/internal/config"
+	"github.com/ankit-lilly/go-datastar-daisyui-template/internal/handlers"
+	"github.com/ankit-lilly/go-datastar-daisyui-template/internal/jobs"
+	"github.com/ankit-lilly/go-datastar-daisyui-template/internal/middleware"
+	"github.com/ankit-lilly/go-datastar-daisyui-template/internal/store"
+	"github.com/ankit-lilly/go-datastar-daisyui-template/internal/views"
+)
+
+func newMaintenanceServer(t *testing.T, cfg *config.Config) *httptest.Server {
+	t.Helper()
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /", func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "ok")
+	})
+	maintenance := middleware.NewMaintenanceMode()
+	adminRouter(mux, cfg)("POST /api/admin/maintenance", setMaintenance(slog.New(slog.DiscardHandler), maintenance))
+
+	srv := httptest.NewServer(maintenance.Middleware("/api/admin/")(mux))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func setMaintenanceReq(t *testing.T, srv *httptest.Server, query string, auth bool) *http.Response {
+	t.Helper()
+	req, err := http.NewRequest(http.MethodPost, srv.URL+"/api/admin/maintenance"+query, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if auth {
+		req.SetBasicAuth("admin", "secret")
+	}
+	resp, err := srv.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	return resp
+}
+
+func pageStatus(t *testing.T, srv *httptest.Server) int {
+	t.Helper()
+	resp, err := srv.Client().Get(srv.URL + "/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	return resp.StatusCode
+}
+
+func TestMaintenanceToggle(t *testing.T) {
+	srv := newMaintenanceServer(t, &config.Config{BasicAuthUser: "admin", BasicAuthPassword: "secret"})
+
+	if got := pageStatus(t, srv); got != http.StatusOK {
+		t.Fatalf("before: status %d, want 200", got)
+	}
+	if resp := setMaintenanceReq(t, srv, "?enabled=true", false); resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("without credentials: status %d, want 401", resp.StatusCode)
+	}
+	if got := pageStatus(t, srv); got != http.StatusOK {
+		t.Fatalf("after unauthorized toggle: status %d, want 200", got)
+	}
+
+	if resp := setMaintenanceReq(t, srv, "?enabled=true", true); resp.StatusCode != http.StatusOK {
+		t.Fatalf("enable: status %d, want 200", resp.StatusCode)
+	}
+	if got := pageStatus(t, srv); got != http.StatusServiceUnavailable {
+		t.Fatalf("enabled: status %d, want 503", got)
+	}
+
+	// Without enabled the endpoint toggles.
+	if resp := setMaintenanceReq(t, srv, "", true); resp.StatusCode != http.StatusOK {
+		t.Fatalf("toggle: status %d, want 200", resp.StatusCode)
+	}
+	if got := pageStatus(t, srv); got != http.StatusOK {
+		t.Fatalf("toggled off: status %d, want 200", got)
+	}
+}
+
+func TestAdminRoutesNeedCredentials(t *testing.T) {
+	srv := newMaintenanceServer(t, &config.Config{})
+
+	resp := setMaintenanceReq(t, srv, "?enabled=true", false)
+	if resp.StatusCode != http.StatusMethodNotAllowed && resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("status %d, want the route to be missing", resp.StatusCode)
+	}
+	if got := pageStatus(t, srv); got != http.StatusOK {
+		t.Fatalf("status %d after toggle attempt, want 200", got)
+	}
+}
+
+func TestSetMaintenanceRejectsBadValue(t *testing.T) {
+	srv := newMaintenanceServer(t, &config.Config{BasicAuthUser: "admin", BasicAuthPassword: "secret"})
+
+	if resp := setMaintenanceReq(t, srv, "?enabled=maybe", true); resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("status %d, want 400", resp.StatusCode)
+	}
+	if got := pageStatus(t, srv); got != http.StatusOK {
+		t.Fatalf("status %d, want 200", got)
+	}
+}
+
+func TestCheckPprof(t *testing.T) {
+	noAuth := &config.Config{}
+	auth := &config.Config{BasicAuthUser: "admin", BasicAuthPassword: "secret"}
+
+	if err := checkPprof(true, noAuth); err == nil {
+		t.Error("pprof without basic auth was allowed")
+	}
+	if err := checkPprof(true, auth); err != nil {
+		t.Errorf("pprof with basic auth: %v", err)
+	}
+	if err := checkPprof(false, noAuth); err != nil {
+		t.Errorf("pprof off without basic auth: %v", err)
+	}
+}
+
+func TestDisabledFeatureRoutes(t *testing.T) {
+	config.Set(&config.Config{Features: map[string]bool{config.FeatureClock: false}})
+	t.Cleanup(func() { config.Set(nil) })
+
+	logger := slog.New(slog.DiscardHandler)
+	h := handlers.New(logger, jobs.NewHub(logger), broadcast.NewHub(logger), store.New[string, *atomic.Int64](time.Hour))
+	mux := http.NewServeMux()
+	handle := func(pattern string, handler http.HandlerFunc) { mux.Handle(pattern, handler) }
+	demoRoutes(mux, h, handle, handle)
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/clock", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("GET /api/clock with clock off = %d, want 404", rec.Code)
+	}
+	if _, pattern := mux.Handler(httptest.NewRequest(http.MethodGet, "/api/counter", nil)); pattern == "" {
+		t.Error("GET /api/counter is not registered with counter on")
+	}
+}
+
+func TestLogRequestsRouteGroup(t *testing.T) {
+	ok := func(w http.ResponseWriter, r *http.Request) {}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /", ok)
+	mux.HandleFunc("GET /app/static/", ok)
+	mux.HandleFunc("GET /favicon.ico", ok)
+	mux.HandleFunc("GET /api/version", ok)
+	mux.HandleFunc("GET /api/clock", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+	})
+
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, nil))
+	var inFlight atomic.Int64
+	handler := logRequests(logger, &inFlight, "/app/static/", middleware.MatchRoute(mux))
+
+	tests := []struct {
+		method, path string
+		want         string
+	}{
+		{http.MethodGet, "/", "page"},
+		{http.MethodGet, "/app/static/css/output.css", "static"},
+		{http.MethodGet, "/api/version", "api"},
+		{http.MethodGet, "/api/clock", "sse"},
+		{http.MethodPost, "/api/version", "unmatched"},
+	}
+	for _, tt := range tests {
+		buf.Reset()
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(tt.method, tt.path, nil))
+
+		var record struct {
+			Path       string `json:"path"`
+			RouteGroup string `json:"route_group"`
+		}
+		if err := json.Unmarshal(buf.Bytes(), &record); err != nil {
+			t.Fatalf("%s %s: invalid log line %q: %v", tt.method, tt.path, buf.String(), err)
+		}
+		if record.RouteGroup != tt.want {
+			t.Errorf("%s %s: route_group %q, want %q", tt.method, tt.path, record.RouteGroup, tt.want)
+		}
+	}
+
+	// Browser-initiated requests aren't logged at all.
+	buf.Reset()
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/favicon.ico", nil))
+	if buf.Len() != 0 {
+		t.Errorf("favicon request logged: %s", buf.String())
+	}
+	if n := inFlight.Load(); n != 0 {
+		t.Errorf("%d requests in flight after all finished, want 0", n)
+	}
+}
+
+func TestStaticPrefix(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "css"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "css", "output.css"), []byte("body{}"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("STATIC_DIR", dir)
+	t.Setenv("STATIC_PREFIX", "app/assets")
+	cfg, err := config.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	views.SetAssetPrefix(cfg.StaticPrefix)
+	t.Cleanup(func() { views.SetAssetPrefix("/static/") })
+
+	mux := http.NewServeMux()
+	mountStatic(mux, cfg, assets.FileServer(cfg.StaticDir))
+
+	url := views.AssetPath("css/output.css")
+	if url != "/app/assets/css/output.css" {
+		t.Fatalf("AssetPath = %q, want it under the configured prefix", url)
+	}
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, url, nil))
+	if rec.Code != http.StatusOK || rec.Body.String() != "body{}" {
+		t.Errorf("GET %s: status %d, body %q; want the file", url, rec.Code, rec.Body)
+	}
+
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/static/css/output.css", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("GET under the default prefix: status %d, want 404", rec.Code)
+	}
+}
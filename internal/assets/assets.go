@@ -0,0 +1,60 @@
+package assets
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// ManifestFile is written by the installer into the static directory and
+// maps logical asset names to their fingerprinted files.
+const ManifestFile = "manifest.json"
+
+// Required lists the assets, by logical name relative to the static
+// directory, that every page needs. They are produced by cmd/install.
+var Required = []string{
+	"css/output.css",
+	"js/datastar.js",
+}
+
+type Manifest map[string]string
+
+// LoadManifest reads the manifest from staticDir. A missing manifest is not
+// an error and yields an empty Manifest.
+func LoadManifest(staticDir string) (Manifest, error) {
+	data, err := os.ReadFile(filepath.Join(staticDir, ManifestFile))
+	if errors.Is(err, fs.ErrNotExist) {
+		return Manifest{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var m Manifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// Resolve returns the fingerprinted name for a logical asset, or name itself
+// when the manifest has no entry.
+func (m Manifest) Resolve(name string) string {
+	if fingerprinted, ok := m[name]; ok {
+		return fingerprinted
+	}
+	return name
+}
+
+// Missing returns the Required assets that are not present in staticDir.
+func Missing(staticDir string, m Manifest) []string {
+	var missing []string
+	for _, name := range Required {
+		if _, err := os.Stat(filepath.Join(staticDir, m.Resolve(name))); err != nil {
+			missing = append(missing, name)
+		}
+	}
+	return missing
+}
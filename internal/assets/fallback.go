@@ -0,0 +1,58 @@
+package assets
+
+import (
+	"bytes"
+	"embed"
+	"html/template"
+	"io/fs"
+	"net/http"
+)
+
+// fallbackFS holds stand-ins for the Required assets and the setup page,
+// used when the installer has not been run.
+//
+//go:embed fallback
+var fallbackFS embed.FS
+
+var setupTemplate = template.Must(template.ParseFS(fallbackFS, "fallback/setup.html"))
+
+// FallbackServer serves the embedded stand-ins for the Required assets: a
+// minimal stylesheet and a datastar.js that only logs a warning. They are
+// never cached, so the real files are picked up once they are installed.
+func FallbackServer() http.Handler {
+	sub, err := fs.Sub(fallbackFS, "fallback")
+	if err != nil {
+		panic(err)
+	}
+	files := http.FileServerFS(sub)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", CacheDevelopment)
+		files.ServeHTTP(w, r)
+	})
+}
+
+// SetupPage serves a page listing the missing assets in staticDir and how
+// to install them. stylesheet is the URL of the fallback stylesheet.
+func SetupPage(staticDir, stylesheet string, missing []string) http.Handler {
+	var buf bytes.Buffer
+	err := setupTemplate.Execute(&buf, struct {
+		StaticDir  string
+		Stylesheet string
+		Missing    []string
+	}{staticDir, stylesheet, missing})
+	if err != nil {
+		panic(err)
+	}
+	page := buf.Bytes()
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Header().Set("Cache-Control", "no-store")
+		w.WriteHeader(http.StatusServiceUnavailable)
+		w.Write(page)
+	})
+}
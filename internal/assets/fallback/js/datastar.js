@@ -0,0 +1,3 @@
+// Stand-in served when static/js/datastar.js is missing. Run
+// `go run ./cmd/install` to download Datastar.
+console.warn("datastar.js is missing; run `go run ./cmd/install` and restart the server.");
@@ -0,0 +1,45 @@
+package assets
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestFallbackWhenStaticDirMissing(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "static")
+	m, err := LoadManifest(dir)
+	if err != nil {
+		t.Fatalf("LoadManifest: %v", err)
+	}
+	missing := Missing(dir, m)
+	if !slices.Equal(missing, Required) {
+		t.Fatalf("Missing = %v, want every required asset %v", missing, Required)
+	}
+
+	files := FallbackServer()
+	for _, name := range Required {
+		rec := httptest.NewRecorder()
+		files.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/"+name, nil))
+		if rec.Code != http.StatusOK || rec.Body.Len() == 0 {
+			t.Errorf("%s: status %d with %d bytes, want the stand-in", name, rec.Code, rec.Body.Len())
+		}
+		if got := rec.Header().Get("Cache-Control"); got != CacheDevelopment {
+			t.Errorf("%s: Cache-Control %q, want %q", name, got, CacheDevelopment)
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	SetupPage(dir, "/static/css/output.css", missing).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("setup page: status %d, want 503", rec.Code)
+	}
+	for _, name := range missing {
+		if !strings.Contains(rec.Body.String(), name) {
+			t.Errorf("setup page does not list %s", name)
+		}
+	}
+}
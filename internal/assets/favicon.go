@@ -0,0 +1,26 @@
+package assets
+
+import (
+	_ "embed"
+	"net/http"
+	"strconv"
+)
+
+//go:embed favicon.svg
+var defaultFavicon []byte
+
+// Favicon serves the file at path, or the embedded default icon when path
+// is empty, so browsers' automatic /favicon.ico requests don't 404.
+func Favicon(path string) http.Handler {
+	if path != "" {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			http.ServeFile(w, r, path)
+		})
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/svg+xml")
+		w.Header().Set("Content-Length", strconv.Itoa(len(defaultFavicon)))
+		w.Header().Set("Cache-Control", "public, max-age=86400")
+		w.Write(defaultFavicon)
+	})
+}
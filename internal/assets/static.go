@@ -0,0 +1,125 @@
+package assets
+
+import (
+	"mime"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// Precompressed lists the encodings FileServer looks for, in order of
+// preference, with the suffix of the precompressed sibling file.
+var Precompressed = []struct {
+	Encoding string
+	Ext      string
+}{
+	{"br", ".br"},
+	{"gzip", ".gz"},
+}
+
+// FileServer serves dir like http.FileServer, but when the client accepts
+// an encoding in Precompressed and a sibling file with its suffix exists
+// (e.g. output.css.br next to output.css), that file is served instead with
+// the matching Content-Encoding. Siblings older than the original are
+// ignored, so a rebuilt asset never gets a stale compressed copy.
+func FileServer(dir string) http.Handler {
+	root := http.Dir(dir)
+	files := http.FileServer(root)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/") {
+			files.ServeHTTP(w, r)
+			return
+		}
+		w.Header().Add("Vary", "Accept-Encoding")
+
+		for _, p := range Precompressed {
+			if !AcceptsEncoding(r, p.Encoding) || !fresh(root, r.URL.Path, p.Ext) {
+				continue
+			}
+			if ctype := mime.TypeByExtension(path.Ext(r.URL.Path)); ctype != "" {
+				w.Header().Set("Content-Type", ctype)
+			}
+			w.Header().Set("Content-Encoding", p.Encoding)
+
+			r2 := r.Clone(r.Context())
+			r2.URL.Path += p.Ext
+			r2.URL.RawPath = ""
+			files.ServeHTTP(w, r2)
+			return
+		}
+		files.ServeHTTP(w, r)
+	})
+}
+
+// Cache-Control values set by CacheControl.
+const (
+	CacheDevelopment = "no-cache"
+	CacheImmutable   = "public, max-age=31536000, immutable"
+	CacheProduction  = "public, max-age=86400"
+)
+
+// CacheControl sets Cache-Control on static responses. In development every
+// request revalidates, so edited files show up on reload; the file server's
+// Last-Modified keeps that to a 304 when nothing changed. In production,
+// fingerprinted files named by m never change and are cached for a year,
+// and everything else for a day.
+func CacheControl(next http.Handler, production bool, m Manifest) http.Handler {
+	fingerprinted := make(map[string]bool, len(m))
+	for _, name := range m {
+		fingerprinted[name] = true
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case !production:
+			w.Header().Set("Cache-Control", CacheDevelopment)
+		case fingerprinted[strings.TrimPrefix(r.URL.Path, "/")]:
+			w.Header().Set("Cache-Control", CacheImmutable)
+		default:
+			w.Header().Set("Cache-Control", CacheProduction)
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// fresh reports whether name+ext exists and is no older than name.
+func fresh(root http.FileSystem, name, ext string) bool {
+	orig, err := root.Open(name)
+	if err != nil {
+		return false
+	}
+	defer orig.Close()
+	origInfo, err := orig.Stat()
+	if err != nil || origInfo.IsDir() {
+		return false
+	}
+
+	f, err := root.Open(name + ext)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil || info.IsDir() {
+		return false
+	}
+	return !info.ModTime().Before(origInfo.ModTime())
+}
+
+// AcceptsEncoding reports whether the request's Accept-Encoding header
+// allows encoding, honouring q=0 as a refusal.
+func AcceptsEncoding(r *http.Request, encoding string) bool {
+	for _, part := range strings.Split(r.Header.Get("Accept-Encoding"), ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		if !strings.EqualFold(strings.TrimSpace(name), encoding) {
+			continue
+		}
+		q, ok := strings.CutPrefix(strings.TrimSpace(params), "q=")
+		if !ok {
+			return true
+		}
+		v, err := strconv.ParseFloat(q, 64)
+		return err == nil && v > 0
+	}
+	return false
+}
@@ -0,0 +1,34 @@
+package assets
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCacheControl(t *testing.T) {
+	m := Manifest{"css/output.css": "css/output.3f9c2a1b.css"}
+	ok := http.HandlerFunc(func(http.ResponseWriter, *http.Request) {})
+
+	tests := []struct {
+		name       string
+		production bool
+		path       string
+		want       string
+	}{
+		{"development", false, "/css/output.3f9c2a1b.css", CacheDevelopment},
+		{"development, unfingerprinted", false, "/img/logo.png", CacheDevelopment},
+		{"production, fingerprinted", true, "/css/output.3f9c2a1b.css", CacheImmutable},
+		{"production, logical name", true, "/css/output.css", CacheProduction},
+		{"production, unfingerprinted", true, "/img/logo.png", CacheProduction},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			CacheControl(ok, tt.production, m).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.path, nil))
+			if got := rec.Header().Get("Cache-Control"); got != tt.want {
+				t.Errorf("Cache-Control %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,131 @@
+// Package audit writes an append-only log of job submissions,
+// cancellations and completions, separate from the access and application
+// logs. Entries are JSON lines so they can be shipped and rotated like any
+// other log file.
+package audit
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"os"
+	"sync"
+	"sync/atomic"
+)
+
+// Event is one audited action.
+type Event struct {
+	// Action is what happened, e.g. "job.submitted" or "job.cancelled".
+	Action string
+	// Actor identifies who caused it: a client ID such as "session:…" or
+	// "ip:…", or "system" for the server itself.
+	Actor   string
+	JobID   string
+	JobName string
+	Status  string
+	Error   string
+	// Detail is free-form context, such as how many jobs a bulk action hit.
+	Detail string
+}
+
+// Logger writes Events to a file. It is safe for concurrent use.
+type Logger struct {
+	path   string
+	out    *reopener
+	logger *slog.Logger
+}
+
+// Open appends to the file at path, creating it if needed; "-" writes to
+// stdout.
+func Open(path string) (*Logger, error) {
+	out := &reopener{path: path}
+	if err := out.open(); err != nil {
+		return nil, err
+	}
+	return &Logger{path: path, out: out, logger: slog.New(slog.NewJSONHandler(out, nil))}, nil
+}
+
+// Record writes e with the current time.
+func (l *Logger) Record(e Event) {
+	attrs := []slog.Attr{slog.String("action", e.Action), slog.String("actor", e.Actor)}
+	for _, a := range []struct{ key, value string }{
+		{"job_id", e.JobID},
+		{"job_name", e.JobName},
+		{"status", e.Status},
+		{"error", e.Error},
+		{"detail", e.Detail},
+	} {
+		if a.value != "" {
+			attrs = append(attrs, slog.String(a.key, a.value))
+		}
+	}
+	l.logger.LogAttrs(context.Background(), slog.LevelInfo, "audit", attrs...)
+}
+
+// Reopen closes and reopens the file, for use after logrotate has moved it.
+func (l *Logger) Reopen() error {
+	return l.out.open()
+}
+
+// Close closes the file.
+func (l *Logger) Close() error {
+	return l.out.close()
+}
+
+// reopener is a writer whose file can be swapped while in use.
+type reopener struct {
+	path string
+	mu   sync.Mutex
+	f    io.WriteCloser
+}
+
+func (r *reopener) open() error {
+	var f io.WriteCloser = nopCloser{os.Stdout}
+	if r.path != "-" {
+		file, err := os.OpenFile(r.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+		if err != nil {
+			return err
+		}
+		f = file
+	}
+
+	r.mu.Lock()
+	old := r.f
+	r.f = f
+	r.mu.Unlock()
+	if old != nil {
+		return old.Close()
+	}
+	return nil
+}
+
+func (r *reopener) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.f.Write(p)
+}
+
+func (r *reopener) close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.f.Close()
+}
+
+type nopCloser struct{ io.Writer }
+
+func (nopCloser) Close() error { return nil }
+
+var current atomic.Pointer[Logger]
+
+// SetDefault makes l the logger Record writes to. A nil l turns auditing
+// off, which is the initial state.
+func SetDefault(l *Logger) {
+	current.Store(l)
+}
+
+// Record writes e to the default logger, if one is set.
+func Record(e Event) {
+	if l := current.Load(); l != nil {
+		l.Record(e)
+	}
+}
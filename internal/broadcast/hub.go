@@ -0,0 +1,112 @@
+package broadcast
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+
+	"github.com/a-h/templ"
+	"github.com/ankit-lilly/go-datastar-daisyui-template/internal/util"
+	"github.com/starfederation/datastar-go/datastar"
+)
+
+var ErrUnknownClient = errors.New("broadcast: unknown client")
+
+// Hub tracks open SSE connections so components can be pushed to every
+// connected client or to a single one.
+type Hub struct {
+	clients map[string]*datastar.ServerSentEventGenerator
+	logger  *slog.Logger
+	mu      sync.RWMutex
+}
+
+func NewHub(logger *slog.Logger) *Hub {
+	return &Hub{
+		clients: make(map[string]*datastar.ServerSentEventGenerator),
+		logger:  logger,
+	}
+}
+
+// Register adds sse to the hub and returns its client ID. The client is
+// removed automatically when the SSE request context is done.
+func (h *Hub) Register(sse *datastar.ServerSentEventGenerator) string {
+	id := util.GenerateID()
+
+	h.mu.Lock()
+	h.clients[id] = sse
+	h.mu.Unlock()
+
+	go func() {
+		<-sse.Context().Done()
+		h.remove(id)
+	}()
+
+	return id
+}
+
+// Broadcast renders component once and patches it into every client.
+// Clients whose write fails are dropped.
+func (h *Hub) Broadcast(component templ.Component, opts ...datastar.PatchElementOption) error {
+	html, err := render(component)
+	if err != nil {
+		return err
+	}
+
+	h.mu.RLock()
+	clients := make(map[string]*datastar.ServerSentEventGenerator, len(h.clients))
+	for id, sse := range h.clients {
+		clients[id] = sse
+	}
+	h.mu.RUnlock()
+
+	for id, sse := range clients {
+		if err := sse.PatchElements(html, opts...); err != nil {
+			h.logger.Debug("broadcast write failed", "client_id", id, "error", err)
+			h.remove(id)
+		}
+	}
+	return nil
+}
+
+// SendTo patches component into a single client.
+func (h *Hub) SendTo(id string, component templ.Component, opts ...datastar.PatchElementOption) error {
+	h.mu.RLock()
+	sse, ok := h.clients[id]
+	h.mu.RUnlock()
+	if !ok {
+		return ErrUnknownClient
+	}
+
+	html, err := render(component)
+	if err != nil {
+		return err
+	}
+	if err := sse.PatchElements(html, opts...); err != nil {
+		h.remove(id)
+		return err
+	}
+	return nil
+}
+
+// Count returns the number of connected clients.
+func (h *Hub) Count() int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return len(h.clients)
+}
+
+func (h *Hub) remove(id string) {
+	h.mu.Lock()
+	delete(h.clients, id)
+	h.mu.Unlock()
+}
+
+func render(component templ.Component) (string, error) {
+	var buf bytes.Buffer
+	if err := component.Render(context.Background(), &buf); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
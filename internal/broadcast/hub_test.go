@@ -0,0 +1,91 @@
+package broadcast
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/a-h/templ"
+	"github.com/starfederation/datastar-go/datastar"
+)
+
+// fakeConn is an SSE connection writing to a recorder.
+type fakeConn struct {
+	rec    *httptest.ResponseRecorder
+	sse    *datastar.ServerSentEventGenerator
+	cancel context.CancelFunc
+}
+
+func newFakeConn(t *testing.T) *fakeConn {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	req := httptest.NewRequestWithContext(ctx, http.MethodGet, "/stream", nil)
+	rec := httptest.NewRecorder()
+	return &fakeConn{rec: rec, sse: datastar.NewSSE(rec, req), cancel: cancel}
+}
+
+func newTestHub() *Hub {
+	return NewHub(slog.New(slog.DiscardHandler))
+}
+
+func TestBroadcastReachesEveryClient(t *testing.T) {
+	h := newTestHub()
+	a, b := newFakeConn(t), newFakeConn(t)
+	h.Register(a.sse)
+	h.Register(b.sse)
+
+	if err := h.Broadcast(templ.Raw(`<div id="msg">hello</div>`)); err != nil {
+		t.Fatalf("Broadcast: %v", err)
+	}
+	for name, c := range map[string]*fakeConn{"a": a, "b": b} {
+		if body := c.rec.Body.String(); !strings.Contains(body, `<div id="msg">hello</div>`) {
+			t.Errorf("client %s got %q", name, body)
+		}
+	}
+}
+
+func TestSendToOneClient(t *testing.T) {
+	h := newTestHub()
+	a, b := newFakeConn(t), newFakeConn(t)
+	id := h.Register(a.sse)
+	h.Register(b.sse)
+	before := b.rec.Body.Len()
+
+	if err := h.SendTo(id, templ.Raw(`<div id="msg">just you</div>`)); err != nil {
+		t.Fatalf("SendTo: %v", err)
+	}
+	if !strings.Contains(a.rec.Body.String(), "just you") {
+		t.Error("target client did not get the patch")
+	}
+	if b.rec.Body.Len() != before {
+		t.Error("other client got the patch")
+	}
+	if err := h.SendTo("nobody", templ.Raw("<div></div>")); !errors.Is(err, ErrUnknownClient) {
+		t.Errorf("unknown client: err = %v, want ErrUnknownClient", err)
+	}
+}
+
+func TestClientRemovedWhenDone(t *testing.T) {
+	h := newTestHub()
+	a, b := newFakeConn(t), newFakeConn(t)
+	h.Register(a.sse)
+	h.Register(b.sse)
+	if n := h.Count(); n != 2 {
+		t.Fatalf("Count = %d, want 2", n)
+	}
+
+	a.cancel()
+	deadline := time.Now().Add(5 * time.Second)
+	for h.Count() != 1 {
+		if time.Now().After(deadline) {
+			t.Fatalf("Count = %d after disconnect, want 1", h.Count())
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
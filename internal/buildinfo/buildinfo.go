@@ -0,0 +1,55 @@
+package buildinfo
+
+import (
+	"runtime"
+	"runtime/debug"
+)
+
+// Set at build time, e.g.
+//
+//	go build -ldflags "-X github.com/ankit-lilly/go-datastar-daisyui-template/internal/buildinfo.Version=v1.2.3"
+var (
+	Version   = "dev"
+	Commit    = ""
+	BuildTime = ""
+)
+
+// DatastarVersion is the Datastar client bundle downloaded by cmd/install.
+const DatastarVersion = "v1.0.0"
+
+type Info struct {
+	Version         string `json:"version"`
+	Commit          string `json:"commit"`
+	BuildTime       string `json:"buildTime"`
+	GoVersion       string `json:"goVersion"`
+	DatastarVersion string `json:"datastarVersion"`
+}
+
+// Get returns the build information. When Commit or BuildTime were not
+// set via -ldflags, they fall back to the VCS stamp embedded by the Go
+// toolchain, if any.
+func Get() Info {
+	info := Info{
+		Version:         Version,
+		Commit:          Commit,
+		BuildTime:       BuildTime,
+		GoVersion:       runtime.Version(),
+		DatastarVersion: DatastarVersion,
+	}
+
+	if bi, ok := debug.ReadBuildInfo(); ok {
+		for _, s := range bi.Settings {
+			switch s.Key {
+			case "vcs.revision":
+				if info.Commit == "" {
+					info.Commit = s.Value
+				}
+			case "vcs.time":
+				if info.BuildTime == "" {
+					info.BuildTime = s.Value
+				}
+			}
+		}
+	}
+	return info
+}
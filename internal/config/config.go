@@ -1,22 +1,539 @@
 package config
 
-import "os"
+import (
+	"bufio"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/netip"
+	"os"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
 
+// Config is read from the environment and, for keys the environment does
+// not set, from the KEY=VALUE file named by CONFIG_FILE. Fields marked
+// reloadable take effect on Reload; the rest require a restart.
 type Config struct {
 	Addr string
-	Env  string
+	// Env is "development" (the default) or "production". It switches
+	// behaviour such as static asset caching; see Production.
+	Env        string
+	ConfigFile string
+
+	// LogLevel is reloadable.
+	LogLevel slog.Level
+
+	SessionSecret string
+	SessionTTL    time.Duration
+
+	// BasicAuthUser and BasicAuthPassword protect the job routes, and pprof
+	// when enabled, when set. The admin routes exist only when they are.
+	BasicAuthUser     string
+	BasicAuthPassword string
+	BasicAuthRealm    string
+
+	// JobStorePath, when set, persists job metadata in a SQLite database at
+	// that path. Requires building with -tags sqlite.
+	JobStorePath string
+
+	// AuditLogPath, when set, appends job submissions, cancellations and
+	// completions as JSON lines to that file; "-" writes them to stdout.
+	AuditLogPath string
+
+	// JobWorkers, when positive, runs jobs on that many long-lived workers
+	// instead of a goroutine per job.
+	JobWorkers int
+
+	// JobMaxPerClient caps the active jobs each client (session or IP) may
+	// have. Zero means no cap.
+	JobMaxPerClient int
+
+	// JobShutdownGrace is how long running jobs may take to finish on
+	// shutdown before they are cancelled. It comes out of the overall
+	// shutdown budget.
+	JobShutdownGrace time.Duration
+
+	// JobQueuePolicy is what happens when the job queue is full: "reject",
+	// "block" (wait up to JobQueueTimeout) or "drop" (the oldest queued
+	// job).
+	JobQueuePolicy  string
+	JobQueueTimeout time.Duration
+
+	// JobIDPrefix, when set, starts every job ID, e.g. with the instance
+	// name, so IDs can be told apart across instances.
+	JobIDPrefix string
+
+	// JobRetention is how long finished jobs, and their result files, are
+	// kept in memory. Zero keeps them until shutdown.
+	JobRetention time.Duration
+
+	// JobUpdateBuffer is the capacity of each job's progress channel.
+	JobUpdateBuffer int
+
+	// JobProgressInterval is the minimum time between a job's progress
+	// updates. Zero sends every one.
+	JobProgressInterval time.Duration
+
+	// JobProgressLogStep is the progress interval, in percent, between
+	// debug log lines for a job. Zero disables them.
+	JobProgressLogStep int
+
+	// SSERetryBase and SSERetryMax bound the reconnect delay sent to
+	// long-lived SSE streams; it doubles for every SSERetryStep open
+	// streams.
+	SSERetryBase time.Duration
+	SSERetryMax  time.Duration
+	SSERetryStep int
+
+	// SSEWriteTimeout bounds each write to a long-lived SSE stream; a
+	// client that cannot take an event in time is dropped. Zero disables it.
+	SSEWriteTimeout time.Duration
+
+	// SSEHeartbeat is how often an idle long-lived SSE stream gets an empty
+	// event, keeping proxies from closing it. Zero disables it.
+	SSEHeartbeat time.Duration
+
+	// SSEMaxStreams caps the long-lived SSE streams open at once; clients
+	// over the cap get 503. Zero means no cap.
+	SSEMaxStreams int
+
+	// RenderTimeout bounds how long a full page render may take. It is
+	// reloadable.
+	RenderTimeout time.Duration
+
+	// RenderCacheTTL is how long rendered static page bodies are reused.
+	// Zero disables the cache.
+	RenderCacheTTL time.Duration
+
+	// RequestTimeout is the deadline for API handlers that answer and
+	// return. Long-lived SSE streams are exempt.
+	RequestTimeout time.Duration
+
+	// EnablePprof mounts net/http/pprof at /debug/pprof/. The server's
+	// -pprof flag enables it too.
+	EnablePprof bool
+
+	// StaticDir is the on-disk directory served under StaticPrefix.
+	StaticDir    string
+	StaticPrefix string
+
+	// GzipLevel is the compression level for static files compressed on the
+	// fly: 1 (fastest) to 9 (smallest), or -1 for gzip's default.
+	GzipLevel int
+
+	// FaviconPath is a file served at /favicon.ico. An embedded icon is
+	// served when it is empty.
+	FaviconPath string
+
+	// Themes are the DaisyUI themes offered by the theme picker and
+	// accepted by POST /api/theme; the first is the default. They must also
+	// be enabled in static/css/input.css to be compiled. They are
+	// reloadable.
+	Themes []string
+
+	// NavItems are the links in the layout's navbar, in order. They are
+	// reloadable.
+	NavItems []NavItem
+
+	// Features switches optional demos on or off by name (see
+	// FeatureNames); missing names are on. Set with
+	// FEATURES=chat=off,wizard=off.
+	Features map[string]bool
+
+	Security Security
+}
+
+// NavItem is a navbar link. Icon is optional short text, such as an emoji,
+// shown before Label. An item with a Feature is hidden while that feature
+// is off.
+type NavItem struct {
+	Label   string
+	Href    string
+	Icon    string
+	Feature string
 }
 
-func Load() *Config {
-	return &Config{
-		Addr: getEnv("ADDR", ":8080"),
-		Env:  getEnv("ENV", "development"),
+// Feature names, as used in FEATURES and NavItem.Feature. The features
+// package re-exports them for templates and route registration.
+const (
+	FeatureCounter = "counter"
+	FeatureClock   = "clock"
+	FeatureJobs    = "jobs"
+	FeatureWizard  = "wizard"
+	FeatureChat    = "chat"
+)
+
+// FeatureNames are the optional demos FEATURES can switch off.
+var FeatureNames = []string{FeatureCounter, FeatureClock, FeatureJobs, FeatureWizard, FeatureChat}
+
+// Security gathers the settings of the security middleware so they are
+// configured in one place.
+type Security struct {
+	// AllowedOrigins may make cross-origin requests (CORS). "*" allows any
+	// origin; empty disables CORS.
+	AllowedOrigins []string
+
+	// TrustedProxies are the networks whose X-Forwarded-For header is
+	// believed when determining the client IP.
+	TrustedProxies []netip.Prefix
+
+	// CSRFSecret signs CSRF tokens. A random secret is used when empty.
+	CSRFSecret string
+
+	// MaxBodyBytes caps request bodies. Zero disables the cap.
+	MaxBodyBytes int64
+
+	// RateLimit is the sustained requests per second allowed per client IP,
+	// with bursts up to RateBurst. Zero disables rate limiting.
+	RateLimit float64
+	RateBurst int
+}
+
+// DefaultThemes are used when THEMES is not set.
+var DefaultThemes = []string{"light", "dark", "cupcake", "forest", "synthwave"}
+
+// Production reports whether the server runs with ENV=production.
+func (c *Config) Production() bool {
+	return c.Env == "production"
+}
+
+var current atomic.Pointer[Config]
+
+// Current returns the active configuration set by Set or Reload. It is
+// safe for concurrent use; callers should not modify the result.
+func Current() *Config {
+	return current.Load()
+}
+
+// Set makes c the active configuration.
+func Set(c *Config) {
+	current.Store(c)
+}
+
+// Reload reads the configuration again and swaps in its reloadable fields.
+// It returns the new configuration and the names of fields that changed
+// but only take effect after a restart. A configuration that fails Validate
+// is rejected and the current one kept.
+func Reload() (*Config, []string, error) {
+	fresh, err := Load()
+	if err != nil {
+		return nil, nil, err
 	}
+	if err := fresh.Validate(); err != nil {
+		return nil, nil, err
+	}
+
+	prev := Current()
+	next := *prev
+	next.LogLevel = fresh.LogLevel
+	next.RenderTimeout = fresh.RenderTimeout
+	next.Themes = fresh.Themes
+	next.NavItems = fresh.NavItems
+	next.Security.RateLimit = fresh.Security.RateLimit
+	next.Security.RateBurst = fresh.Security.RateBurst
+
+	var ignored []string
+	pv, fv := reflect.ValueOf(next), reflect.ValueOf(*fresh)
+	for i := range pv.NumField() {
+		if !reflect.DeepEqual(pv.Field(i).Interface(), fv.Field(i).Interface()) {
+			ignored = append(ignored, pv.Type().Field(i).Name)
+		}
+	}
+
+	current.Store(&next)
+	return &next, ignored, nil
 }
 
-func getEnv(key, fallback string) string {
+func Load() (*Config, error) {
+	path := os.Getenv("CONFIG_FILE")
+	file, err := readFile(path)
+	if err != nil {
+		return nil, err
+	}
+	env := &envSource{file: file}
+
+	proxies, err := parsePrefixes(env.list("TRUSTED_PROXIES"))
+	if err != nil {
+		env.errs = append(env.errs, fmt.Errorf("config: TRUSTED_PROXIES: %w", err))
+	}
+	features, err := parseFeatures(env.list("FEATURES"))
+	if err != nil {
+		env.errs = append(env.errs, fmt.Errorf("config: FEATURES: %w", err))
+	}
+
+	cfg := &Config{
+		Addr:          env.str("ADDR", ":8080"),
+		Env:           env.str("ENV", "development"),
+		ConfigFile:    path,
+		LogLevel:      env.level("LOG_LEVEL", slog.LevelInfo),
+		SessionSecret: env.str("SESSION_SECRET", ""),
+		SessionTTL:    env.duration("SESSION_TTL", 24*time.Hour),
+
+		BasicAuthUser:     env.str("BASIC_AUTH_USER", ""),
+		BasicAuthPassword: env.str("BASIC_AUTH_PASSWORD", ""),
+		BasicAuthRealm:    env.str("BASIC_AUTH_REALM", "Restricted"),
+
+		JobStorePath:    env.str("JOB_STORE_PATH", ""),
+		AuditLogPath:    env.str("AUDIT_LOG_PATH", ""),
+		JobWorkers:      env.int("JOB_WORKERS", 0),
+		JobMaxPerClient: env.int("JOB_MAX_PER_CLIENT", 3),
+		JobUpdateBuffer: env.int("JOB_UPDATE_BUFFER", 100),
+		JobIDPrefix:     env.str("JOB_ID_PREFIX", ""),
+
+		JobQueuePolicy:   env.str("JOB_QUEUE_POLICY", "reject"),
+		JobQueueTimeout:  env.duration("JOB_QUEUE_TIMEOUT", 5*time.Second),
+		JobShutdownGrace: env.duration("JOB_SHUTDOWN_GRACE", 10*time.Second),
+		JobRetention:     env.duration("JOB_RETENTION", time.Hour),
+
+		JobProgressInterval: env.duration("JOB_PROGRESS_INTERVAL", 100*time.Millisecond),
+		JobProgressLogStep:  env.int("JOB_PROGRESS_LOG_STEP", 10),
+
+		SSERetryBase: env.duration("SSE_RETRY_BASE", time.Second),
+		SSERetryMax:  env.duration("SSE_RETRY_MAX", 30*time.Second),
+		SSERetryStep: env.int("SSE_RETRY_STEP", 50),
+
+		SSEMaxStreams:   env.int("SSE_MAX_STREAMS", 1000),
+		SSEWriteTimeout: env.duration("SSE_WRITE_TIMEOUT", 10*time.Second),
+		SSEHeartbeat:    env.duration("SSE_HEARTBEAT", 15*time.Second),
+
+		RenderTimeout:  env.duration("RENDER_TIMEOUT", 5*time.Second),
+		RenderCacheTTL: env.duration("RENDER_CACHE_TTL", time.Minute),
+		RequestTimeout: env.duration("REQUEST_TIMEOUT", 10*time.Second),
+
+		EnablePprof: env.bool("ENABLE_PPROF", false),
+
+		StaticDir:    env.str("STATIC_DIR", "static"),
+		StaticPrefix: normalizePrefix(env.str("STATIC_PREFIX", "/static/")),
+		GzipLevel:    env.int("GZIP_LEVEL", gzip.DefaultCompression),
+		FaviconPath:  env.str("FAVICON_PATH", ""),
+
+		Themes:   env.listOr("THEMES", DefaultThemes),
+		Features: features,
+
+		NavItems: []NavItem{
+			{Label: "Home", Href: "/", Icon: "🏠"},
+			{Label: "Counter", Href: "/#counter-demo", Feature: FeatureCounter},
+			{Label: "Clock", Href: "/#clock-demo", Feature: FeatureClock},
+			{Label: "Jobs", Href: "/#jobs-demo", Feature: FeatureJobs},
+			{Label: "Wizard", Href: "/#wizard-demo", Feature: FeatureWizard},
+			{Label: "Chat", Href: "/#chat-demo", Feature: FeatureChat},
+			{Label: "Datastar docs", Href: "https://data-star.dev/guide", Icon: "📖"},
+		},
+
+		Security: Security{
+			AllowedOrigins: env.list("CORS_ALLOWED_ORIGINS"),
+			TrustedProxies: proxies,
+			CSRFSecret:     env.str("CSRF_SECRET", ""),
+			MaxBodyBytes:   int64(env.int("MAX_BODY_BYTES", 1<<20)),
+			RateLimit:      env.float("RATE_LIMIT", 0),
+			RateBurst:      env.int("RATE_LIMIT_BURST", 20),
+		},
+	}
+	// Report every unparsable value at once, as Validate does.
+	if err := errors.Join(env.errs...); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// parsePrefixes parses CIDRs, accepting bare addresses as single-host
+// networks.
+func parsePrefixes(values []string) ([]netip.Prefix, error) {
+	prefixes := make([]netip.Prefix, 0, len(values))
+	for _, v := range values {
+		if !strings.Contains(v, "/") {
+			addr, err := netip.ParseAddr(v)
+			if err != nil {
+				return nil, err
+			}
+			prefixes = append(prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+			continue
+		}
+		p, err := netip.ParsePrefix(v)
+		if err != nil {
+			return nil, err
+		}
+		prefixes = append(prefixes, p.Masked())
+	}
+	return prefixes, nil
+}
+
+// parseFeatures parses name=on|off entries; the value is anything
+// strconv.ParseBool accepts, or on/off.
+func parseFeatures(values []string) (map[string]bool, error) {
+	features := make(map[string]bool, len(values))
+	for _, v := range values {
+		name, value, ok := strings.Cut(v, "=")
+		if !ok {
+			return nil, fmt.Errorf("%q: want name=on or name=off", v)
+		}
+		var on bool
+		switch value = strings.ToLower(strings.TrimSpace(value)); value {
+		case "on":
+			on = true
+		case "off":
+		default:
+			b, err := strconv.ParseBool(value)
+			if err != nil {
+				return nil, fmt.Errorf("%q: want name=on or name=off", v)
+			}
+			on = b
+		}
+		features[strings.TrimSpace(name)] = on
+	}
+	return features, nil
+}
+
+// readFile parses a KEY=VALUE file, skipping blank lines and # comments.
+// An empty path yields no values.
+func readFile(path string) (map[string]string, error) {
+	values := make(map[string]string)
+	if path == "" {
+		return values, nil
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("config: %w", err)
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			return nil, fmt.Errorf("config: %s:%d: expected KEY=VALUE", path, n)
+		}
+		values[strings.TrimSpace(key)] = strings.Trim(strings.TrimSpace(value), `"'`)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("config: %w", err)
+	}
+	return values, nil
+}
+
+// envSource looks keys up in the process environment first, then in the
+// config file values. Values that don't parse are recorded in errs, naming
+// the variable, and the fallback is used so Load can report them all.
+type envSource struct {
+	file map[string]string
+	errs []error
+}
+
+func (e *envSource) lookup(key string) string {
 	if v := os.Getenv(key); v != "" {
 		return v
 	}
+	return e.file[key]
+}
+
+func (e *envSource) invalid(key, value, want string) {
+	e.errs = append(e.errs, fmt.Errorf("config: %s %q: must be %s", key, value, want))
+}
+
+func (e *envSource) str(key, fallback string) string {
+	if v := e.lookup(key); v != "" {
+		return v
+	}
 	return fallback
 }
+
+func (e *envSource) duration(key string, fallback time.Duration) time.Duration {
+	v := e.lookup(key)
+	if v == "" {
+		return fallback
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		e.invalid(key, v, "a duration such as 10s or 1m30s")
+		return fallback
+	}
+	return d
+}
+
+func (e *envSource) int(key string, fallback int) int {
+	v := e.lookup(key)
+	if v == "" {
+		return fallback
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		e.invalid(key, v, "an integer")
+		return fallback
+	}
+	return n
+}
+
+func (e *envSource) bool(key string, fallback bool) bool {
+	v := e.lookup(key)
+	if v == "" {
+		return fallback
+	}
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		e.invalid(key, v, "true or false")
+		return fallback
+	}
+	return b
+}
+
+func (e *envSource) float(key string, fallback float64) float64 {
+	v := e.lookup(key)
+	if v == "" {
+		return fallback
+	}
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		e.invalid(key, v, "a number")
+		return fallback
+	}
+	return f
+}
+
+// list splits a comma-separated value, dropping empty items.
+func (e *envSource) list(key string) []string {
+	var items []string
+	for item := range strings.SplitSeq(e.lookup(key), ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// listOr is like list but returns a copy of fallback when the key is unset
+// or lists nothing.
+func (e *envSource) listOr(key string, fallback []string) []string {
+	if items := e.list(key); len(items) > 0 {
+		return items
+	}
+	return slices.Clone(fallback)
+}
+
+func (e *envSource) level(key string, fallback slog.Level) slog.Level {
+	v := e.lookup(key)
+	if v == "" {
+		return fallback
+	}
+	var l slog.Level
+	if err := l.UnmarshalText([]byte(v)); err != nil {
+		e.invalid(key, v, "debug, info, warn or error")
+		return fallback
+	}
+	return l
+}
+
+// normalizePrefix ensures a route prefix starts and ends with a slash.
+func normalizePrefix(p string) string {
+	return "/" + strings.Trim(p, "/") + "/"
+}
@@ -0,0 +1,128 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoadDefaultsAreValid(t *testing.T) {
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("Validate: %v", err)
+	}
+}
+
+func TestLoadReportsUnparsableValues(t *testing.T) {
+	env := map[string]string{
+		"JOB_WORKERS":     "four",
+		"SESSION_TTL":     "abc",
+		"ENABLE_PPROF":    "sometimes",
+		"RATE_LIMIT":      "fast",
+		"LOG_LEVEL":       "loud",
+		"TRUSTED_PROXIES": "not-an-ip",
+	}
+	for k, v := range env {
+		t.Setenv(k, v)
+	}
+
+	_, err := Load()
+	if err == nil {
+		t.Fatal("Load accepted unparsable values")
+	}
+	for k, v := range env {
+		if !strings.Contains(err.Error(), k) || !strings.Contains(err.Error(), v) {
+			t.Errorf("error does not name %s=%s:\n%v", k, v, err)
+		}
+	}
+}
+
+func TestLoadReadsConfigFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.env")
+	if err := os.WriteFile(path, []byte("JOB_WORKERS=4\nREQUEST_TIMEOUT=oops\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("CONFIG_FILE", path)
+
+	_, err := Load()
+	if err == nil || !strings.Contains(err.Error(), "REQUEST_TIMEOUT") {
+		t.Fatalf("err = %v, want REQUEST_TIMEOUT named", err)
+	}
+
+	// The environment wins over the file.
+	t.Setenv("REQUEST_TIMEOUT", "3s")
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if cfg.JobWorkers != 4 || cfg.RequestTimeout != 3*time.Second {
+		t.Fatalf("JobWorkers = %d, RequestTimeout = %s; want 4, 3s", cfg.JobWorkers, cfg.RequestTimeout)
+	}
+}
+
+func TestValidateRejectsBadValues(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		mutate func(*Config)
+		want   string
+	}{
+		{"addr", func(c *Config) { c.Addr = "8080" }, "ADDR"},
+		{"env", func(c *Config) { c.Env = "staging" }, "ENV"},
+		{"negative workers", func(c *Config) { c.JobWorkers = -1 }, "JOB_WORKERS"},
+		{"zero session ttl", func(c *Config) { c.SessionTTL = 0 }, "SESSION_TTL"},
+		{"retry max below base", func(c *Config) { c.SSERetryMax = c.SSERetryBase / 2 }, "SSE_RETRY_MAX"},
+		{"queue policy", func(c *Config) { c.JobQueuePolicy = "maybe" }, "JOB_QUEUE_POLICY"},
+		{"gzip level", func(c *Config) { c.GzipLevel = 11 }, "GZIP_LEVEL"},
+		{"job id prefix", func(c *Config) { c.JobIDPrefix = "a/b" }, "JOB_ID_PREFIX"},
+		{"half basic auth", func(c *Config) { c.BasicAuthUser = "admin" }, "BASIC_AUTH_PASSWORD"},
+		{"unknown feature", func(c *Config) { c.Features = map[string]bool{"teleport": true} }, "FEATURES"},
+		{"theme", func(c *Config) { c.Themes = []string{"system"} }, "THEMES"},
+		{"rate burst", func(c *Config) { c.Security.RateLimit, c.Security.RateBurst = 5, 0 }, "RATE_LIMIT_BURST"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg, err := Load()
+			if err != nil {
+				t.Fatalf("Load: %v", err)
+			}
+			tc.mutate(cfg)
+			err = cfg.Validate()
+			if err == nil || !strings.Contains(err.Error(), tc.want) {
+				t.Fatalf("Validate = %v, want an error naming %s", err, tc.want)
+			}
+		})
+	}
+}
+
+func TestReloadRateLimits(t *testing.T) {
+	cfg, err := Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	Set(cfg)
+	t.Cleanup(func() { Set(nil) })
+
+	t.Setenv("RATE_LIMIT", "5")
+	t.Setenv("RATE_LIMIT_BURST", "10")
+	t.Setenv("JOB_WORKERS", "3")
+	next, ignored, err := Reload()
+	if err != nil {
+		t.Fatalf("Reload: %v", err)
+	}
+	if next.Security.RateLimit != 5 || next.Security.RateBurst != 10 {
+		t.Fatalf("rate limit %v/%d, want 5/10", next.Security.RateLimit, next.Security.RateBurst)
+	}
+	if Current() != next {
+		t.Fatal("Current does not return the reloaded config")
+	}
+	if next.JobWorkers != cfg.JobWorkers {
+		t.Fatalf("JobWorkers reloaded to %d", next.JobWorkers)
+	}
+	if len(ignored) != 1 || ignored[0] != "JobWorkers" {
+		t.Fatalf("ignored = %v, want [JobWorkers]", ignored)
+	}
+}
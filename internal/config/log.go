@@ -0,0 +1,32 @@
+package config
+
+import "log/slog"
+
+// LogValue summarises the resolved configuration for the startup log.
+// Secrets are never logged, only whether they are set.
+func (c *Config) LogValue() slog.Value {
+	set := func(s string) bool { return s != "" }
+	return slog.GroupValue(
+		slog.String("env", c.Env),
+		slog.String("config_file", c.ConfigFile),
+		slog.String("log_level", c.LogLevel.String()),
+		slog.String("static_dir", c.StaticDir),
+		slog.String("static_prefix", c.StaticPrefix),
+		slog.Int("job_workers", c.JobWorkers),
+		slog.String("job_id_prefix", c.JobIDPrefix),
+		slog.Int("job_max_per_client", c.JobMaxPerClient),
+		slog.String("job_queue_policy", c.JobQueuePolicy),
+		slog.String("job_store", c.JobStorePath),
+		slog.String("audit_log", c.AuditLogPath),
+		slog.Int("sse_max_streams", c.SSEMaxStreams),
+		slog.Duration("request_timeout", c.RequestTimeout),
+		slog.Any("themes", c.Themes),
+		slog.Any("features", c.Features),
+		slog.Bool("basic_auth", set(c.BasicAuthUser)),
+		slog.Bool("session_secret_set", set(c.SessionSecret)),
+		slog.Bool("csrf_secret_set", set(c.Security.CSRFSecret)),
+		slog.Any("allowed_origins", c.Security.AllowedOrigins),
+		slog.Int("trusted_proxies", len(c.Security.TrustedProxies)),
+		slog.Float64("rate_limit", c.Security.RateLimit),
+	)
+}
@@ -0,0 +1,64 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestLogValue(t *testing.T) {
+	t.Setenv("SESSION_SECRET", "session-secret-value")
+	t.Setenv("BASIC_AUTH_USER", "admin")
+	t.Setenv("BASIC_AUTH_PASSWORD", "admin-password-value")
+	t.Setenv("JOB_WORKERS", "4")
+	t.Setenv("THEMES", "light,dark")
+	cfg, err := Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	slog.New(slog.NewJSONHandler(&buf, nil)).Info("starting", "config", cfg)
+	for _, secret := range []string{"session-secret-value", "admin-password-value"} {
+		if strings.Contains(buf.String(), secret) {
+			t.Errorf("startup log leaks a secret:\n%s", buf.String())
+		}
+	}
+
+	var record struct {
+		Config map[string]any `json:"config"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &record); err != nil {
+		t.Fatalf("invalid JSON %q: %v", buf.String(), err)
+	}
+	want := map[string]any{
+		"env":                cfg.Env,
+		"static_prefix":      "/static/",
+		"job_workers":        float64(4),
+		"basic_auth":         true,
+		"session_secret_set": true,
+		"csrf_secret_set":    false,
+		"themes":             []any{"light", "dark"},
+	}
+	for key, value := range want {
+		got, ok := record.Config[key]
+		if !ok {
+			t.Errorf("config lacks %s", key)
+			continue
+		}
+		if g, w := toJSON(t, got), toJSON(t, value); g != w {
+			t.Errorf("%s = %s, want %s", key, g, w)
+		}
+	}
+}
+
+func toJSON(t *testing.T, v any) string {
+	t.Helper()
+	b, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
@@ -0,0 +1,131 @@
+package config
+
+import (
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// jobIDPrefixRe keeps job ID prefixes URL-safe, since IDs appear in paths.
+var jobIDPrefixRe = regexp.MustCompile(`^[A-Za-z0-9._-]{1,32}$`)
+
+// Validate checks c for values that would otherwise fail confusingly at
+// runtime. It reports every problem at once, each naming the variable to
+// fix.
+func (c *Config) Validate() error {
+	var errs []error
+	check := func(ok bool, format string, args ...any) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, args...))
+		}
+	}
+
+	if err := validateAddr(c.Addr); err != nil {
+		errs = append(errs, fmt.Errorf("ADDR %q: %w", c.Addr, err))
+	}
+	check(c.Env == "development" || c.Env == "production",
+		"ENV %q: must be development or production", c.Env)
+
+	for _, d := range []struct {
+		name     string
+		value    time.Duration
+		positive bool
+	}{
+		{"SESSION_TTL", c.SessionTTL, true},
+		{"RENDER_TIMEOUT", c.RenderTimeout, true},
+		{"SSE_RETRY_BASE", c.SSERetryBase, true},
+		{"SSE_RETRY_MAX", c.SSERetryMax, true},
+		{"RENDER_CACHE_TTL", c.RenderCacheTTL, false},
+		{"REQUEST_TIMEOUT", c.RequestTimeout, false},
+		{"SSE_WRITE_TIMEOUT", c.SSEWriteTimeout, false},
+		{"SSE_HEARTBEAT", c.SSEHeartbeat, false},
+		{"JOB_QUEUE_TIMEOUT", c.JobQueueTimeout, true},
+		{"JOB_SHUTDOWN_GRACE", c.JobShutdownGrace, false},
+		{"JOB_RETENTION", c.JobRetention, false},
+		{"JOB_PROGRESS_INTERVAL", c.JobProgressInterval, false},
+	} {
+		if d.positive {
+			check(d.value > 0, "%s %s: must be positive", d.name, d.value)
+		} else {
+			check(d.value >= 0, "%s %s: must not be negative", d.name, d.value)
+		}
+	}
+	check(c.SSERetryMax >= c.SSERetryBase,
+		"SSE_RETRY_MAX %s: must be at least SSE_RETRY_BASE (%s)", c.SSERetryMax, c.SSERetryBase)
+
+	for _, n := range []struct {
+		name  string
+		value int
+	}{
+		{"JOB_WORKERS", c.JobWorkers},
+		{"JOB_MAX_PER_CLIENT", c.JobMaxPerClient},
+		{"JOB_PROGRESS_LOG_STEP", c.JobProgressLogStep},
+		{"SSE_RETRY_STEP", c.SSERetryStep},
+		{"SSE_MAX_STREAMS", c.SSEMaxStreams},
+	} {
+		check(n.value >= 0, "%s %d: must not be negative", n.name, n.value)
+	}
+	check(slices.Contains([]string{"reject", "block", "drop"}, c.JobQueuePolicy),
+		"JOB_QUEUE_POLICY %q: must be reject, block or drop", c.JobQueuePolicy)
+	check(c.JobUpdateBuffer >= 1, "JOB_UPDATE_BUFFER %d: must be at least 1", c.JobUpdateBuffer)
+	check(c.GzipLevel == gzip.DefaultCompression || (c.GzipLevel >= gzip.BestSpeed && c.GzipLevel <= gzip.BestCompression),
+		"GZIP_LEVEL %d: must be 1 to 9, or -1 for the default", c.GzipLevel)
+	check(c.JobIDPrefix == "" || jobIDPrefixRe.MatchString(c.JobIDPrefix),
+		"JOB_ID_PREFIX %q: must be at most 32 letters, digits, '.', '_' or '-'", c.JobIDPrefix)
+
+	check((c.BasicAuthUser == "") == (c.BasicAuthPassword == ""),
+		"BASIC_AUTH_USER and BASIC_AUTH_PASSWORD must be set together")
+	if c.FaviconPath != "" {
+		if _, err := os.Stat(c.FaviconPath); err != nil {
+			errs = append(errs, fmt.Errorf("FAVICON_PATH: %w", err))
+		}
+	}
+
+	for name := range c.Features {
+		check(slices.Contains(FeatureNames, name),
+			"FEATURES: unknown feature %q; known: %s", name, strings.Join(FeatureNames, ", "))
+	}
+
+	seen := make(map[string]bool)
+	for _, theme := range c.Themes {
+		// "system" is the picker's follow-the-browser choice, not a theme.
+		check(theme != "system" && !strings.ContainsAny(theme, ` "'<>`),
+			"THEMES: %q is not a valid theme name", theme)
+		check(!seen[theme], "THEMES: %q is listed twice", theme)
+		seen[theme] = true
+	}
+
+	for i, item := range c.NavItems {
+		check(item.Label != "" && item.Href != "", "nav item %d: needs a label and an href", i)
+		check(item.Feature == "" || slices.Contains(FeatureNames, item.Feature),
+			"nav item %d: unknown feature %q", i, item.Feature)
+	}
+
+	sec := c.Security
+	check(sec.MaxBodyBytes >= 0, "MAX_BODY_BYTES %d: must not be negative", sec.MaxBodyBytes)
+	check(sec.RateLimit >= 0, "RATE_LIMIT %g: must not be negative", sec.RateLimit)
+	check(sec.RateLimit == 0 || sec.RateBurst >= 1,
+		"RATE_LIMIT_BURST %d: must be at least 1 when RATE_LIMIT is set", sec.RateBurst)
+
+	return errors.Join(errs...)
+}
+
+// validateAddr checks addr is host:port with a numeric port, as
+// net/http's ListenAndServe expects.
+func validateAddr(addr string) error {
+	_, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return err
+	}
+	if n, err := strconv.Atoi(port); err != nil || n < 0 || n > 65535 {
+		return fmt.Errorf("invalid port %q", port)
+	}
+	return nil
+}
@@ -0,0 +1,28 @@
+// Package features reports which optional demos are switched on. The flags
+// come from FEATURES (see config.Config.Features) and are read at startup:
+// main registers a disabled feature's routes not at all, and templates use
+// Enabled to leave out its section and nav items.
+package features
+
+import "github.com/ankit-lilly/go-datastar-daisyui-template/internal/config"
+
+// Feature names, as used in FEATURES and NavItem.Feature. They are
+// defined in config, which validates FEATURES against the same list.
+const (
+	Counter = config.FeatureCounter
+	Clock   = config.FeatureClock
+	Jobs    = config.FeatureJobs
+	Wizard  = config.FeatureWizard
+	Chat    = config.FeatureChat
+)
+
+// Enabled reports whether the named feature is on. Features are on unless
+// FEATURES turns them off.
+func Enabled(name string) bool {
+	cfg := config.Current()
+	if cfg == nil {
+		return true
+	}
+	on, ok := cfg.Features[name]
+	return !ok || on
+}
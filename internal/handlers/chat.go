@@ -0,0 +1,96 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ankit-lilly/go-datastar-daisyui-template/internal/broadcast"
+	"github.com/ankit-lilly/go-datastar-daisyui-template/internal/signals"
+	"github.com/ankit-lilly/go-datastar-daisyui-template/internal/sseutil"
+	"github.com/ankit-lilly/go-datastar-daisyui-template/internal/views"
+	"github.com/starfederation/datastar-go/datastar"
+)
+
+const chatHistorySize = 50
+
+// chatRoom keeps the most recent messages so new subscribers can catch up.
+// The mutex is held while broadcasting and while a subscriber replays the
+// history and registers, so no message is missed or delivered twice.
+type chatRoom struct {
+	hub     *broadcast.Hub
+	history []views.ChatEntry
+	mu      sync.Mutex
+}
+
+func newChatRoom(hub *broadcast.Hub) *chatRoom {
+	return &chatRoom{hub: hub}
+}
+
+func (c *chatRoom) post(e views.ChatEntry) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.history = append(c.history, e)
+	if len(c.history) > chatHistorySize {
+		c.history = c.history[len(c.history)-chatHistorySize:]
+	}
+	return c.hub.Broadcast(views.ChatMessage(e),
+		datastar.WithSelectorID(views.ChatMessagesID),
+		datastar.WithModeAppend(),
+	)
+}
+
+func (c *chatRoom) subscribe(sse *datastar.ServerSentEventGenerator) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if len(c.history) > 0 {
+		err := sseutil.Patch(sse, sse.Context(), views.ChatMessages(c.history),
+			sseutil.WithTarget(views.ChatMessagesID),
+			sseutil.WithMode(datastar.ElementPatchModeInner),
+		)
+		if err != nil {
+			return err
+		}
+	}
+	c.hub.Register(sse)
+	return nil
+}
+
+func (h *Handlers) PostMessage(w http.ResponseWriter, r *http.Request) {
+	req, err := signals.Decode[struct {
+		Text string `json:"text" validate:"notblank,max=500"`
+	}](r)
+	if err != nil {
+		signals.BadRequest(w, err)
+		return
+	}
+	text := strings.TrimSpace(req.Text)
+
+	// Message text is only ever rendered through templ, which escapes it.
+	if err := h.chat.post(views.ChatEntry{Text: text, SentAt: time.Now()}); err != nil {
+		h.logger.Error("chat broadcast failed", "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	sse, ok := h.streams.Start(w, r)
+	if !ok {
+		return
+	}
+	sse.MarshalAndPatchSignals(map[string]any{"text": ""})
+}
+
+func (h *Handlers) MessagesStream(w http.ResponseWriter, r *http.Request) {
+	h.streams.Handle(func(ctx context.Context, sse *datastar.ServerSentEventGenerator) error {
+		if err := h.chat.subscribe(sse); err != nil {
+			return fmt.Errorf("chat subscribe: %w", err)
+		}
+		<-ctx.Done()
+		return nil
+	}).ServeHTTP(w, r)
+}
@@ -0,0 +1,35 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/ankit-lilly/go-datastar-daisyui-template/internal/views"
+	"github.com/starfederation/datastar-go/datastar"
+)
+
+// clockInterval is how often Clock pushes the time.
+const clockInterval = time.Second
+
+// Clock streams the server time every second until the client disconnects.
+// Unlike the one-shot counter it keeps the request open, so the ticker must
+// be stopped when the stream's context ends.
+func (h *Handlers) Clock(w http.ResponseWriter, r *http.Request) {
+	h.streams.Handle(h.clock).ServeHTTP(w, r)
+}
+
+func (h *Handlers) clock(ctx context.Context, sse *datastar.ServerSentEventGenerator) error {
+	ticker := time.NewTicker(clockInterval)
+	defer ticker.Stop()
+
+	h.patch(sse, views.Clock(time.Now()))
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case now := <-ticker.C:
+			h.patch(sse, views.Clock(now))
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"testing/synctest"
+	"time"
+
+	"github.com/starfederation/datastar-go/datastar"
+)
+
+// lockedRecorder is a ResponseRecorder the stream can write to while the
+// test reads it.
+type lockedRecorder struct {
+	mu sync.Mutex
+	*httptest.ResponseRecorder
+}
+
+func (r *lockedRecorder) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.ResponseRecorder.Write(p)
+}
+
+func (r *lockedRecorder) body() string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.Body.String()
+}
+
+func TestClockTicksUntilCancelled(t *testing.T) {
+	h, _ := newTestHandlers(t)
+	synctest.Test(t, func(t *testing.T) {
+		rec := &lockedRecorder{ResponseRecorder: httptest.NewRecorder()}
+		sse := datastar.NewSSE(rec, httptest.NewRequest(http.MethodGet, "/api/clock", nil))
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan error)
+		go func() { done <- h.clock(ctx, sse) }()
+
+		patches := func() int {
+			synctest.Wait()
+			return strings.Count(rec.body(), "event: datastar-patch-elements")
+		}
+		// The time right away, then once per interval.
+		if n := patches(); n != 1 {
+			t.Fatalf("%d patches at start, want 1", n)
+		}
+		time.Sleep(2*clockInterval + clockInterval/2)
+		if n := patches(); n != 3 {
+			t.Fatalf("%d patches after two intervals, want 3", n)
+		}
+
+		cancel()
+		if err := <-done; err != nil {
+			t.Fatalf("clock = %v, want nil", err)
+		}
+		time.Sleep(2 * clockInterval)
+		if n := patches(); n != 3 {
+			t.Fatalf("%d patches after cancelling, want still 3", n)
+		}
+	})
+}
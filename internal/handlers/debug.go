@@ -0,0 +1,40 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/ankit-lilly/go-datastar-daisyui-template/internal/signals"
+	"github.com/ankit-lilly/go-datastar-daisyui-template/internal/views"
+)
+
+// DebugSignals echoes the signals the client sent as pretty-printed JSON, so
+// developers can see what the server receives. It is only mounted outside
+// production.
+func (h *Handlers) DebugSignals(w http.ResponseWriter, r *http.Request) {
+	received, err := signals.DecodeAny(r)
+	if err != nil {
+		signals.BadRequest(w, err)
+		return
+	}
+
+	// Map keys are sorted, so the output is stable between requests. HTML
+	// is left unescaped to show exactly what was sent; templ escapes it
+	// when rendering.
+	var pretty strings.Builder
+	enc := json.NewEncoder(&pretty)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(received); err != nil {
+		h.logger.Error("failed to encode signals", "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	sse, ok := h.streams.Start(w, r)
+	if !ok {
+		return
+	}
+	h.patch(sse, views.SignalsDebug(strings.TrimSuffix(pretty.String(), "\n")))
+}
@@ -0,0 +1,61 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/ankit-lilly/go-datastar-daisyui-template/internal/config"
+	"github.com/ankit-lilly/go-datastar-daisyui-template/internal/sseutil"
+	"github.com/ankit-lilly/go-datastar-daisyui-template/internal/views"
+)
+
+// Error codes sent in JSON error bodies. They are stable; messages are not.
+const (
+	errCodeBadRequest = "bad_request"
+	errCodeNotFound   = "not_found"
+	errCodeConflict   = "conflict"
+)
+
+// respondJSONError writes {"error": {"code": ..., "message": ...}} with
+// status.
+func respondJSONError(w http.ResponseWriter, status int, code, message string) {
+	var body struct {
+		Error struct {
+			Code    string `json:"code"`
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	body.Error.Code = code
+	body.Error.Message = message
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
+
+// apiError answers an API request that failed: with a JSON error for
+// clients that ask for JSON (see wantsJSON), with an error alert for
+// Datastar, and as plain text, like http.Error, for everything else.
+func apiError(w http.ResponseWriter, r *http.Request, status int, code, message string) {
+	switch {
+	case wantsJSON(r):
+		respondJSONError(w, status, code, message)
+	case r.Header.Get("Datastar-Request") == "true":
+		datastarError(w, r, status, message)
+	default:
+		http.Error(w, http.StatusText(status)+": "+message, status)
+	}
+}
+
+// datastarError answers a Datastar request with status and an event stream
+// that appends a DaisyUI error alert to the toast container. Datastar
+// applies the events of error responses too, so the user sees why the
+// action failed.
+func datastarError(w http.ResponseWriter, r *http.Request, status int, message string) {
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(status)
+	sse := sseutil.NewSSE(w, r, config.Current().SSEWriteTimeout)
+	sseutil.Toast(sse, views.ToastError, message)
+}
@@ -0,0 +1,63 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ankit-lilly/go-datastar-daisyui-template/internal/config"
+)
+
+func TestAPIErrorPerClient(t *testing.T) {
+	config.Set(&config.Config{SSEWriteTimeout: time.Second})
+
+	for _, tc := range []struct {
+		name        string
+		header      string
+		value       string
+		contentType string
+		check       func(t *testing.T, body string)
+	}{
+		{"json", "Accept", "application/json", "application/json", func(t *testing.T, body string) {
+			var got struct {
+				Error struct{ Code, Message string }
+			}
+			if err := json.Unmarshal([]byte(body), &got); err != nil {
+				t.Fatalf("body is not JSON: %v\n%s", err, body)
+			}
+			if got.Error.Code != errCodeNotFound || got.Error.Message != "job abc not found" {
+				t.Fatalf("error = %+v", got.Error)
+			}
+		}},
+		{"datastar", "Datastar-Request", "true", "text/event-stream", func(t *testing.T, body string) {
+			for _, want := range []string{"event: datastar-patch-elements", "alert-error", "job abc not found", "selector #toasts"} {
+				if !strings.Contains(body, want) {
+					t.Fatalf("body lacks %q:\n%s", want, body)
+				}
+			}
+		}},
+		{"browser", "Accept", "text/html", "text/plain; charset=utf-8", func(t *testing.T, body string) {
+			if body != "Not Found: job abc not found\n" {
+				t.Fatalf("body %q", body)
+			}
+		}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/api/jobs/abc", nil)
+			r.Header.Set(tc.header, tc.value)
+			rec := httptest.NewRecorder()
+			apiError(rec, r, http.StatusNotFound, errCodeNotFound, "job abc not found")
+
+			if rec.Code != http.StatusNotFound {
+				t.Fatalf("status %d, want 404", rec.Code)
+			}
+			if ct := rec.Header().Get("Content-Type"); ct != tc.contentType {
+				t.Fatalf("Content-Type %q, want %q", ct, tc.contentType)
+			}
+			tc.check(t, rec.Body.String())
+		})
+	}
+}
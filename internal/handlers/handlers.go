@@ -1,30 +1,74 @@
 package handlers
 
 import (
-	"bytes"
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
+	"net"
 	"net/http"
+	"slices"
+	"strings"
 	"sync/atomic"
 	"time"
 
-	"github.com/a-h/templ"
+	"github.com/ankit-lilly/go-datastar-daisyui-template/internal/audit"
+	"github.com/ankit-lilly/go-datastar-daisyui-template/internal/broadcast"
+	"github.com/ankit-lilly/go-datastar-daisyui-template/internal/buildinfo"
+	"github.com/ankit-lilly/go-datastar-daisyui-template/internal/config"
 	"github.com/ankit-lilly/go-datastar-daisyui-template/internal/jobs"
+	"github.com/ankit-lilly/go-datastar-daisyui-template/internal/lifecycle"
+	"github.com/ankit-lilly/go-datastar-daisyui-template/internal/middleware"
+	"github.com/ankit-lilly/go-datastar-daisyui-template/internal/rendercache"
+	"github.com/ankit-lilly/go-datastar-daisyui-template/internal/session"
+	"github.com/ankit-lilly/go-datastar-daisyui-template/internal/signals"
+	"github.com/ankit-lilly/go-datastar-daisyui-template/internal/sseutil"
+	"github.com/ankit-lilly/go-datastar-daisyui-template/internal/store"
 	"github.com/ankit-lilly/go-datastar-daisyui-template/internal/views"
 	"github.com/starfederation/datastar-go/datastar"
 )
 
 type Handlers struct {
-	logger  *slog.Logger
-	jobHub  *jobs.Hub
-	counter atomic.Int64
+	logger *slog.Logger
+	jobHub *jobs.Hub
+	chat   *chatRoom
+	// streams sets up and tears down the long-lived SSE streams.
+	streams *sseutil.Streams
+	pages   *rendercache.Cache
+	// started is the Last-Modified time of rendered pages.
+	started time.Time
+
+	// counter is shared by requests without a session; sessions get their
+	// own entry in counters, keyed by session ID. The optimistic counter
+	// keeps its attempts there too (see optimisticAttempts).
+	counter  atomic.Int64
+	counters *store.Store[string, *atomic.Int64]
 }
 
-func New(logger *slog.Logger, jobHub *jobs.Hub) *Handlers {
+func New(logger *slog.Logger, jobHub *jobs.Hub, broadcaster *broadcast.Hub, counters *store.Store[string, *atomic.Int64]) *Handlers {
 	return &Handlers{
-		logger: logger,
-		jobHub: jobHub,
+		logger:   logger,
+		jobHub:   jobHub,
+		chat:     newChatRoom(broadcaster),
+		streams:  newStreams(logger, config.Current()),
+		pages:    rendercache.New(config.Current().RenderCacheTTL),
+		started:  time.Now(),
+		counters: counters,
+	}
+}
+
+func newStreams(logger *slog.Logger, cfg *config.Config) *sseutil.Streams {
+	return &sseutil.Streams{
+		Limiter: sseutil.NewLimiter(cfg.SSEMaxStreams),
+		Retry: &sseutil.RetryPolicy{
+			Base:           cfg.SSERetryBase,
+			Max:            cfg.SSERetryMax,
+			StreamsPerStep: cfg.SSERetryStep,
+		},
+		WriteTimeout: cfg.SSEWriteTimeout,
+		Heartbeat:    cfg.SSEHeartbeat,
+		Logger:       logger,
 	}
 }
 
@@ -34,74 +78,352 @@ func (h *Handlers) Index(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := views.IndexPage().Render(r.Context(), w); err != nil {
-		h.logger.Error("template render error", "error", err)
-		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+	// The body only depends on the theme list and the environment, which
+	// needs a restart to change, so a reload that changes the list gets a
+	// fresh entry instead of a stale picker.
+	cfg := config.Current()
+	key := "index:" + strings.Join(cfg.Themes, ",")
+	h.renderPage(w, r, views.IndexPage(h.pages.Component(key, views.IndexContent(!cfg.Production()))))
+}
+
+func (h *Handlers) Version(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(buildinfo.Get()); err != nil {
+		h.logger.Error("failed to encode version", "error", err)
 	}
 }
 
+// healthPingTimeout bounds how long a deep health check waits for the ping
+// job.
+const healthPingTimeout = 2 * time.Second
+
+// Healthz reports liveness. With ?deep=1 it also runs a ping job through the
+// job hub and reports degraded (503) if it doesn't complete in time, which
+// catches a full submit queue or a hung run loop.
+func (h *Handlers) Healthz(w http.ResponseWriter, r *http.Request) {
+	status, code := "ok", http.StatusOK
+	checks := map[string]string{}
+
+	if r.URL.Query().Get("deep") == "1" {
+		ctx, cancel := context.WithTimeout(r.Context(), healthPingTimeout)
+		defer cancel()
+		start := time.Now()
+		if err := h.jobHub.Ping(ctx); err != nil {
+			h.logger.Warn("health check failed", "check", "jobs", "error", err)
+			status, code = "degraded", http.StatusServiceUnavailable
+			checks["jobs"] = err.Error()
+		} else {
+			checks["jobs"] = "ok (" + time.Since(start).Round(time.Microsecond).String() + ")"
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(code)
+	body := struct {
+		Status string            `json:"status"`
+		Checks map[string]string `json:"checks,omitempty"`
+	}{status, checks}
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		h.logger.Error("failed to encode health", "error", err)
+	}
+}
+
+// Counter sends the current count: as a Datastar patch to browsers, or as
+// {"count": n} to clients that ask for JSON.
 func (h *Handlers) Counter(w http.ResponseWriter, r *http.Request) {
-	sse := datastar.NewSSE(w, r)
+	count := h.currentCount(r)
+
+	if wantsJSON(r) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "no-store")
+		if err := json.NewEncoder(w).Encode(map[string]int64{"count": count}); err != nil {
+			h.logger.Error("failed to encode counter", "error", err)
+		}
+		return
+	}
 
-	count := h.counter.Load()
-	html := renderComponent(r.Context(), views.CounterValue(count))
-	sse.PatchElements(html)
+	if h.clientGone(r) {
+		return
+	}
+	sse, ok := h.streams.Start(w, r)
+	if !ok {
+		return
+	}
+	h.patch(sse, views.CounterValue(count))
+}
+
+func (h *Handlers) currentCount(r *http.Request) int64 {
+	return h.counterFor(r).Load()
+}
+
+// wantsJSON reports whether the client asked for JSON rather than an event
+// stream. Datastar requests always get SSE, since Datastar's Accept header
+// lists application/json too.
+func wantsJSON(r *http.Request) bool {
+	if r.Header.Get("Datastar-Request") == "true" {
+		return false
+	}
+	accept := r.Header.Get("Accept")
+	return strings.Contains(accept, "application/json") && !strings.Contains(accept, "text/event-stream")
 }
 
 func (h *Handlers) Increment(w http.ResponseWriter, r *http.Request) {
-	sse := datastar.NewSSE(w, r)
+	// Don't count clicks from clients that already went away.
+	if h.clientGone(r) {
+		return
+	}
+	sse, ok := h.streams.Start(w, r)
+	if !ok {
+		return
+	}
 
-	count := h.counter.Add(1)
-	html := renderComponent(r.Context(), views.CounterValue(count))
-	sse.PatchElements(html)
+	count := h.counterFor(r).Add(1)
+	h.patch(sse, views.CounterValue(count))
 }
 
+// IncrementBy adds the step signal (1 to 1000) to the counter and patches
+// the new value along with a counterDelta signal the page uses to animate
+// the change.
+func (h *Handlers) IncrementBy(w http.ResponseWriter, r *http.Request) {
+	req, err := signals.Decode[struct {
+		Step int64 `json:"step" validate:"min=1,max=1000"`
+	}](r)
+	if err != nil {
+		signals.BadRequest(w, err)
+		return
+	}
+	if h.clientGone(r) {
+		return
+	}
+	sse, ok := h.streams.Start(w, r)
+	if !ok {
+		return
+	}
+
+	count := h.counterFor(r).Add(req.Step)
+	h.patch(sse, views.CounterValue(count))
+	sse.MarshalAndPatchSignals(map[string]any{"counterDelta": req.Step})
+}
+
+func (h *Handlers) counterFor(r *http.Request) *atomic.Int64 {
+	s := session.FromContext(r.Context())
+	if s == nil {
+		return &h.counter
+	}
+
+	// Keep the count alive for as long as the session is in use.
+	c, loaded := h.counters.LoadOrStore(s.ID, new(atomic.Int64))
+	if loaded {
+		h.counters.Touch(s.ID)
+	}
+	return c
+}
+
+func (h *Handlers) SetTheme(w http.ResponseWriter, r *http.Request) {
+	req, err := signals.Decode[struct {
+		ThemeRequest string `json:"themeRequest" validate:"required"`
+	}](r)
+	if err != nil {
+		signals.BadRequest(w, err)
+		return
+	}
+
+	theme := req.ThemeRequest
+	if theme != views.ThemeSystem && !slices.Contains(config.Current().Themes, theme) {
+		http.Error(w, "Unknown theme", http.StatusBadRequest)
+		return
+	}
+
+	// The cookie must be set before the SSE response writes its headers.
+	cookie := &http.Cookie{
+		Name:     middleware.ThemeCookieName,
+		Value:    theme,
+		Path:     "/",
+		MaxAge:   365 * 24 * 60 * 60,
+		SameSite: http.SameSiteLaxMode,
+	}
+	if theme == views.ThemeSystem {
+		cookie.Value = ""
+		cookie.MaxAge = -1
+	}
+	http.SetCookie(w, cookie)
+
+	sse, ok := h.streams.Start(w, r)
+	if !ok {
+		return
+	}
+	sse.MarshalAndPatchSignals(map[string]any{"_theme": theme})
+	sseutil.Toast(sse, views.ToastInfo, "Theme changed to "+theme)
+}
+
+// StartJob starts the demo job and streams its progress on the same
+// connection. The job keeps running if the client goes away.
 func (h *Handlers) StartJob(w http.ResponseWriter, r *http.Request) {
-	sse := datastar.NewSSE(w, r)
+	h.runJob(w, r, false)
+}
+
+// RunJob is like StartJob, but the job belongs to the request: it is
+// cancelled when the client disconnects before it finishes.
+func (h *Handlers) RunJob(w http.ResponseWriter, r *http.Request) {
+	h.runJob(w, r, true)
+}
 
+func (h *Handlers) runJob(w http.ResponseWriter, r *http.Request, cancelOnDisconnect bool) {
+	h.streams.Handle(func(ctx context.Context, sse *datastar.ServerSentEventGenerator) error {
+		h.streamJob(ctx, r, sse, cancelOnDisconnect)
+		return nil
+	}).ServeHTTP(w, r)
+}
+
+// closedByServer reports whether ctx ended because the server closed the
+// stream rather than because the client went away.
+func closedByServer(ctx context.Context) bool {
+	cause := context.Cause(ctx)
+	return errors.Is(cause, lifecycle.ErrShuttingDown) || errors.Is(cause, middleware.ErrMaintenance)
+}
+
+// streamJob submits the demo job and streams its updates until it finishes
+// or ctx ends.
+func (h *Handlers) streamJob(ctx context.Context, r *http.Request, sse *datastar.ServerSentEventGenerator, cancelOnDisconnect bool) {
 	job := h.jobHub.NewJob("demo-task", func(j *jobs.Job) error {
+		// The report is spooled to disk and downloaded from
+		// /api/job/{id}/result once the job completes.
+		j.SetResultType("text/csv", "demo-report.csv")
+		report := j.ResultWriter()
+		if _, err := fmt.Fprintln(report, "step,progress,time"); err != nil {
+			return err
+		}
 		for i := 0; i <= 100; i += 10 {
 			select {
 			case <-j.Context().Done():
 				return j.Context().Err()
 			default:
+				if err := j.WaitIfPaused(); err != nil {
+					return err
+				}
 				j.SetProgress(i)
+				j.Log("step %d of 10", i/10)
+				if _, err := fmt.Fprintf(report, "%d,%d,%s\n", i/10, i, time.Now().Format(time.RFC3339)); err != nil {
+					return err
+				}
 				time.Sleep(500 * time.Millisecond)
 			}
 		}
 		return nil
 	})
+	job.Owner = clientID(r)
+
+	if err := h.jobHub.SubmitContext(r.Context(), job); err != nil {
+		h.logger.Warn("job rejected", "job_id", job.ID, "error", err)
+		message := "Server busy, try again"
+		switch {
+		case errors.Is(err, jobs.ErrOwnerLimit):
+			message = "You have too many jobs running; wait for one to finish"
+		case errors.Is(err, jobs.ErrHubStopped):
+			message = "Server is shutting down, try again shortly"
+		}
+		sse.MarshalAndPatchSignals(map[string]any{"jobId": job.ID, "jobStatus": jobs.StatusRejected, "jobProgress": 0})
+		h.patch(sse, views.JobInfo(job.ID, "alert-warning", message))
+		sseutil.Toast(sse, views.ToastWarning, message)
+		return
+	}
 
-	h.jobHub.Submit(job)
+	sse.MarshalAndPatchSignals(map[string]any{"jobId": job.ID, "jobStatus": "running", "jobProgress": 0, "jobEta": ""})
+	h.patch(sse, views.JobInfo(job.ID, "alert-info", "Job started"))
+	h.patch(sse, views.JobLog(nil))
 
-	sse.PatchSignals([]byte(fmt.Sprintf(`{"jobId": "%s", "jobStatus": "running", "jobProgress": 0}`, job.ID)))
-	html := renderComponent(r.Context(), views.JobInfo(job.ID, "alert-info", "Job started"))
-	sse.PatchElements(html)
+	for {
+		var update jobs.JobUpdate
+		select {
+		case <-ctx.Done():
+			// A stream closed by the server, for shutdown or maintenance,
+			// leaves the job running; on shutdown the hub's grace period
+			// decides its fate.
+			if cancelOnDisconnect && !closedByServer(ctx) {
+				h.logger.Info("client disconnected, cancelling job", "job_id", job.ID)
+				job.Cancel()
+				audit.Record(audit.Event{
+					Action:  "job.cancelled",
+					Actor:   job.Owner,
+					JobID:   job.ID,
+					JobName: job.Name,
+					Detail:  "client disconnected",
+				})
+				return
+			}
+			// The job keeps running; only the stream to this client stops.
+			h.logger.Debug("client disconnected from job stream", "job_id", job.ID)
+			return
+		case u, ok := <-job.Updates():
+			if !ok {
+				return
+			}
+			update = u
+		}
 
-	for update := range job.Updates() {
-		sse.PatchSignals([]byte(fmt.Sprintf(`{"jobProgress": %d}`, update.Progress)))
+		sse.MarshalAndPatchSignals(map[string]any{"jobProgress": update.Progress, "jobEta": views.ETAText(update.ETA)})
+		if update.Status != "" {
+			sse.MarshalAndPatchSignals(map[string]any{"jobStatus": update.Status})
+		}
+		if update.LogLine != "" {
+			err := sseutil.Patch(sse, sse.Context(), views.JobLogLine(update.LogLine),
+				sseutil.WithTarget(views.JobLogID),
+				sseutil.WithMode(datastar.ElementPatchModeAppend),
+			)
+			if err != nil && !sse.IsClosed() {
+				h.logger.Error("job log patch failed", "job_id", job.ID, "error", err)
+			}
+		}
 
 		if update.Done {
 			status := "completed"
 			alertClass := "alert-success"
+			toastLevel := views.ToastSuccess
 			message := "Job completed!"
 			if update.Error != nil {
 				status = "failed"
 				alertClass = "alert-error"
+				toastLevel = views.ToastError
 				message = "Job failed: " + update.Error.Error()
+				var jerr *jobs.JobError
+				var perr *jobs.PanicError
+				switch {
+				case errors.As(update.Error, &perr):
+					// The panic value and stack are in the server log only.
+					message = "Job failed: internal error"
+				case errors.As(update.Error, &jerr):
+					message = fmt.Sprintf("Job failed (%s): %v", jerr.Kind, jerr.Err)
+				}
 			}
-			infoHTML := renderComponent(r.Context(), views.JobInfo(job.ID, alertClass, message))
-			sse.PatchElements(infoHTML)
-			sse.PatchSignals([]byte(fmt.Sprintf(`{"jobStatus": "%s"}`, status)))
-			break
+			h.patch(sse, views.JobInfo(job.ID, alertClass, message))
+			sse.MarshalAndPatchSignals(map[string]any{"jobStatus": status})
+			sseutil.Toast(sse, toastLevel, message)
+			return
 		}
 	}
 }
 
-func renderComponent(ctx context.Context, component templ.Component) string {
-	var buf bytes.Buffer
-	if err := component.Render(ctx, &buf); err != nil {
-		return ""
+// clientID identifies the client for per-client limits: its session, or
+// its address when it has none. RemoteAddr is already the real client IP
+// behind trusted proxies (see middleware.RealIP).
+func clientID(r *http.Request) string {
+	if s := session.FromContext(r.Context()); s != nil {
+		return "session:" + s.ID
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	return "ip:" + host
+}
+
+// clientGone reports whether the client disconnected before the handler
+// started writing, logging it at debug level.
+func (h *Handlers) clientGone(r *http.Request) bool {
+	if r.Context().Err() == nil {
+		return false
 	}
-	return buf.String()
+	h.logger.Debug("client disconnected", "method", r.Method, "path", r.URL.Path)
+	return true
 }
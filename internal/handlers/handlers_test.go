@@ -0,0 +1,241 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ankit-lilly/go-datastar-daisyui-template/internal/broadcast"
+	"github.com/ankit-lilly/go-datastar-daisyui-template/internal/config"
+	"github.com/ankit-lilly/go-datastar-daisyui-template/internal/jobs"
+	"github.com/ankit-lilly/go-datastar-daisyui-template/internal/middleware"
+	"github.com/ankit-lilly/go-datastar-daisyui-template/internal/session"
+	"github.com/ankit-lilly/go-datastar-daisyui-template/internal/store"
+)
+
+// newTestHandlers returns Handlers backed by a running job hub built with
+// opts, under a minimal configuration.
+func newTestHandlers(t *testing.T, opts ...jobs.Option) (*Handlers, *jobs.Hub) {
+	t.Helper()
+	config.Set(&config.Config{
+		SSERetryBase: time.Second,
+		SSERetryMax:  time.Second,
+	})
+	logger := slog.New(slog.DiscardHandler)
+	hub := jobs.NewHub(logger, opts...)
+	go hub.Run()
+	t.Cleanup(hub.Stop)
+
+	counters := store.New[string, *atomic.Int64](time.Hour)
+	return New(logger, hub, broadcast.NewHub(logger), counters), hub
+}
+
+// newSessionServer serves handler behind the session middleware.
+func newSessionServer(t *testing.T, handler http.Handler) *httptest.Server {
+	t.Helper()
+	sessions := session.NewManager([]byte("test secret"), time.Hour)
+	srv := httptest.NewServer(sessions.Middleware(handler))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// newSessionClient returns a client with its own cookie jar, and so its own
+// session.
+func newSessionClient(t *testing.T) *http.Client {
+	t.Helper()
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &http.Client{Jar: jar}
+}
+
+// datastarDo sends signals the way Datastar does, in the query for GET and
+// as the body otherwise, and returns the response status and body.
+func datastarDo(t *testing.T, client *http.Client, method, target string, signals any) (int, string) {
+	t.Helper()
+	payload, err := json.Marshal(signals)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var body io.Reader
+	if method == http.MethodGet {
+		target += "?" + url.Values{"datastar": {string(payload)}}.Encode()
+	} else {
+		body = bytes.NewReader(payload)
+	}
+	req, err := http.NewRequest(method, target, body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Datastar-Request", "true")
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	out, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp.StatusCode, string(out)
+}
+
+func TestIncrementBy(t *testing.T) {
+	h, _ := newTestHandlers(t)
+
+	tests := []struct {
+		body       string
+		wantStatus int
+		wantCount  int64
+	}{
+		{`{"step":5}`, http.StatusOK, 5},
+		{`{"step":1000}`, http.StatusOK, 1005},
+		{`{"step":0}`, http.StatusBadRequest, 1005},
+		{`{"step":-3}`, http.StatusBadRequest, 1005},
+		{`{"step":1001}`, http.StatusBadRequest, 1005},
+		{`{}`, http.StatusBadRequest, 1005},
+		{`{"step":"ten"}`, http.StatusBadRequest, 1005},
+		{`{"step":1}`, http.StatusOK, 1006},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodPost, "/api/increment/by", strings.NewReader(tt.body))
+		req.Header.Set("Datastar-Request", "true")
+		rec := httptest.NewRecorder()
+		h.IncrementBy(rec, req)
+
+		if rec.Code != tt.wantStatus {
+			t.Errorf("%s: status %d, want %d", tt.body, rec.Code, tt.wantStatus)
+		}
+		if got := h.counter.Load(); got != tt.wantCount {
+			t.Errorf("%s: counter %d, want %d", tt.body, got, tt.wantCount)
+		}
+		if tt.wantStatus != http.StatusOK {
+			continue
+		}
+		body := rec.Body.String()
+		wantValue := fmt.Sprintf(`<span id="counter-value">%d</span>`, tt.wantCount)
+		step := strings.TrimSuffix(strings.TrimPrefix(tt.body, `{"step":`), "}")
+		for _, want := range []string{wantValue, `"counterDelta":` + step} {
+			if !strings.Contains(body, want) {
+				t.Errorf("%s: body lacks %q:\n%s", tt.body, want, body)
+			}
+		}
+	}
+}
+
+func TestCounterNegotiates(t *testing.T) {
+	h, _ := newTestHandlers(t)
+	h.counter.Store(7)
+
+	tests := []struct {
+		name     string
+		accept   string
+		datastar bool
+		wantCT   string
+		want     string
+	}{
+		{"json", "application/json", false, "application/json", `{"count":7}`},
+		{"event stream", "text/event-stream", false, "text/event-stream", `<span id="counter-value">7</span>`},
+		// Datastar's Accept lists application/json too, but it gets SSE.
+		{"datastar", "text/event-stream, application/json", true, "text/event-stream", `<span id="counter-value">7</span>`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/api/counter", nil)
+			req.Header.Set("Accept", tt.accept)
+			if tt.datastar {
+				req.Header.Set("Datastar-Request", "true")
+			}
+			rec := httptest.NewRecorder()
+			h.Counter(rec, req)
+
+			if ct := rec.Header().Get("Content-Type"); ct != tt.wantCT {
+				t.Errorf("Content-Type %q, want %q", ct, tt.wantCT)
+			}
+			if !strings.Contains(rec.Body.String(), tt.want) {
+				t.Errorf("body lacks %q:\n%s", tt.want, rec.Body)
+			}
+		})
+	}
+}
+
+func TestCounterPerSession(t *testing.T) {
+	h, _ := newTestHandlers(t)
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /api/increment", h.Increment)
+	srv := newSessionServer(t, mux)
+	alice, bob := newSessionClient(t), newSessionClient(t)
+
+	var body string
+	for range 3 {
+		_, body = datastarDo(t, alice, http.MethodPost, srv.URL+"/api/increment", map[string]any{})
+	}
+	if want := `<span id="counter-value">3</span>`; !strings.Contains(body, want) {
+		t.Errorf("first session: body lacks %q:\n%s", want, body)
+	}
+	_, body = datastarDo(t, bob, http.MethodPost, srv.URL+"/api/increment", map[string]any{})
+	if want := `<span id="counter-value">1</span>`; !strings.Contains(body, want) {
+		t.Errorf("second session: body lacks %q:\n%s", want, body)
+	}
+	if got := h.counter.Load(); got != 0 {
+		t.Errorf("shared counter %d, want 0 with sessions", got)
+	}
+}
+
+func TestSetTheme(t *testing.T) {
+	h, _ := newTestHandlers(t)
+	config.Set(&config.Config{Themes: []string{"light", "dark"}})
+
+	tests := []struct {
+		theme      string
+		wantStatus int
+		wantCookie string // "" for no cookie, "-" for a deleted one
+	}{
+		{"dark", http.StatusOK, "dark"},
+		{"system", http.StatusOK, "-"},
+		{"neon", http.StatusBadRequest, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.theme, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/api/theme", strings.NewReader(`{"themeRequest":"`+tt.theme+`"}`))
+			req.Header.Set("Datastar-Request", "true")
+			rec := httptest.NewRecorder()
+			h.SetTheme(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status %d, want %d", rec.Code, tt.wantStatus)
+			}
+			cookies := rec.Result().Cookies()
+			switch {
+			case tt.wantCookie == "":
+				if len(cookies) != 0 {
+					t.Errorf("cookies %v, want none", cookies)
+				}
+			case len(cookies) != 1 || cookies[0].Name != middleware.ThemeCookieName:
+				t.Errorf("cookies %v, want the theme cookie", cookies)
+			case tt.wantCookie == "-":
+				if cookies[0].MaxAge >= 0 {
+					t.Errorf("cookie %v, want it deleted", cookies[0])
+				}
+			case cookies[0].Value != tt.wantCookie:
+				t.Errorf("cookie value %q, want %q", cookies[0].Value, tt.wantCookie)
+			}
+			if tt.wantStatus == http.StatusOK {
+				if want := `"_theme":"` + tt.theme + `"`; !strings.Contains(rec.Body.String(), want) {
+					t.Errorf("body lacks %q:\n%s", want, rec.Body)
+				}
+			}
+		})
+	}
+}
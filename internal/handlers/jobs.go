@@ -0,0 +1,299 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ankit-lilly/go-datastar-daisyui-template/internal/audit"
+	"github.com/ankit-lilly/go-datastar-daisyui-template/internal/jobs"
+	"github.com/ankit-lilly/go-datastar-daisyui-template/internal/servertiming"
+	"github.com/ankit-lilly/go-datastar-daisyui-template/internal/sseutil"
+	"github.com/ankit-lilly/go-datastar-daisyui-template/internal/views"
+)
+
+const (
+	jobListDefaultPageSize = 20
+	jobListMaxPageSize     = 100
+)
+
+// JobsList renders one page of the job history. Query parameters: status,
+// sort (repeatable; a field, optionally suffixed :asc or :desc), order (the
+// default direction, desc unless asc), page and pageSize. Out-of-range
+// numbers are clamped; unknown values are rejected.
+func (h *Handlers) JobsList(w http.ResponseWriter, r *http.Request) {
+	filter, err := parseJobFilter(r)
+	if err != nil {
+		apiError(w, r, http.StatusBadRequest, errCodeBadRequest, err.Error())
+		return
+	}
+
+	state := h.jobPage(r, filter)
+
+	sse, ok := h.streams.Start(w, r)
+	if !ok {
+		return
+	}
+	h.patch(sse, views.JobList(state))
+}
+
+// jobListItem is one job in the JobsListJSON response.
+type jobListItem struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name"`
+	Status    string    `json:"status"`
+	Progress  int       `json:"progress"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// JobsListJSON is JobsList for scripts: the same page, taking the same
+// query parameters, as a JSON array.
+func (h *Handlers) JobsListJSON(w http.ResponseWriter, r *http.Request) {
+	filter, err := parseJobFilter(r)
+	if err != nil {
+		apiError(w, r, http.StatusBadRequest, errCodeBadRequest, err.Error())
+		return
+	}
+
+	state := h.jobPage(r, filter)
+	items := make([]jobListItem, 0, len(state.Jobs))
+	for _, job := range state.Jobs {
+		items = append(items, jobListItem{
+			ID:        job.ID,
+			Name:      job.Name,
+			Status:    job.Status,
+			Progress:  job.Progress,
+			CreatedAt: job.CreatedAt,
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	if err := json.NewEncoder(w).Encode(items); err != nil {
+		h.logger.Error("failed to encode job list", "error", err)
+	}
+}
+
+// jobPage looks up the page of jobs filter asks for.
+func (h *Handlers) jobPage(r *http.Request, filter jobs.JobFilter) views.JobListState {
+	start := time.Now()
+	found, total := h.jobHub.Query(filter)
+
+	// Clamp the page to the last one, e.g. after the filter shrank the list.
+	if pages := max((total+filter.PageSize-1)/filter.PageSize, 1); filter.Page > pages {
+		filter.Page = pages
+		found, total = h.jobHub.Query(filter)
+	}
+	servertiming.Since(r.Context(), "db", start)
+
+	state := views.JobListState{Filter: filter, Total: total}
+	for _, job := range found {
+		state.Jobs = append(state.Jobs, job.Snapshot())
+	}
+	return state
+}
+
+func parseJobFilter(r *http.Request) (jobs.JobFilter, error) {
+	q := r.URL.Query()
+	filter := jobs.JobFilter{
+		Status:   q.Get("status"),
+		Page:     1,
+		PageSize: jobListDefaultPageSize,
+	}
+
+	if filter.Status != "" && !slices.Contains(views.JobStatuses, filter.Status) {
+		return filter, errors.New("invalid status")
+	}
+
+	// order is the direction of sort keys that don't give their own.
+	desc := true
+	switch q.Get("order") {
+	case "", "desc":
+	case "asc":
+		desc = false
+	default:
+		return filter, errors.New("invalid order")
+	}
+
+	for _, v := range q["sort"] {
+		field, dir, hasDir := strings.Cut(v, ":")
+		if !jobs.IsSortField(field) {
+			return filter, errors.New("invalid sort")
+		}
+		key := jobs.SortKey{Field: field, Desc: desc}
+		switch {
+		case !hasDir:
+		case dir == "asc":
+			key.Desc = false
+		case dir == "desc":
+			key.Desc = true
+		default:
+			return filter, errors.New("invalid sort")
+		}
+		filter.Sort = append(filter.Sort, key)
+	}
+	if len(filter.Sort) == 0 {
+		filter.Sort = []jobs.SortKey{{Field: jobs.SortCreated, Desc: desc}}
+	}
+
+	if v := q.Get("page"); v != "" {
+		page, err := strconv.Atoi(v)
+		if err != nil {
+			return filter, errors.New("invalid page")
+		}
+		filter.Page = max(page, 1)
+	}
+	if v := q.Get("pageSize"); v != "" {
+		size, err := strconv.Atoi(v)
+		if err != nil {
+			return filter, errors.New("invalid pageSize")
+		}
+		filter.PageSize = min(max(size, 1), jobListMaxPageSize)
+	}
+
+	return filter, nil
+}
+
+// PauseJob pauses a running job. The job's work must call WaitIfPaused for
+// the pause to take effect.
+func (h *Handlers) PauseJob(w http.ResponseWriter, r *http.Request) {
+	h.setJobPaused(w, r, true)
+}
+
+// ResumeJob resumes a paused job.
+func (h *Handlers) ResumeJob(w http.ResponseWriter, r *http.Request) {
+	h.setJobPaused(w, r, false)
+}
+
+func (h *Handlers) setJobPaused(w http.ResponseWriter, r *http.Request, pause bool) {
+	job, ok := h.jobHub.Get(r.PathValue("id"))
+	if !ok {
+		apiError(w, r, http.StatusNotFound, errCodeNotFound, "job not found")
+		return
+	}
+
+	var message string
+	switch {
+	case pause && job.Pause():
+		message = "Job paused"
+	case pause:
+		apiError(w, r, http.StatusConflict, errCodeConflict, "job is not running")
+		return
+	case job.Resume():
+		message = "Job resumed"
+	default:
+		apiError(w, r, http.StatusConflict, errCodeConflict, "job is not paused")
+		return
+	}
+
+	// The job's own stream patches jobStatus from the emitted update.
+	sse, ok := h.streams.Start(w, r)
+	if !ok {
+		return
+	}
+	sseutil.Toast(sse, views.ToastInfo, message)
+}
+
+// RerunJob runs a job again as a new job with the same work. Clients asking
+// for JSON get the new job's ID and status; browsers get a toast.
+func (h *Handlers) RerunJob(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	job, ok := h.jobHub.Clone(id)
+	if !ok {
+		apiError(w, r, http.StatusNotFound, errCodeNotFound, "job not found or cannot be rerun")
+		return
+	}
+	view := job.Snapshot()
+	audit.Record(audit.Event{
+		Action:  "job.rerun",
+		Actor:   clientID(r),
+		JobID:   job.ID,
+		JobName: job.Name,
+		Detail:  "rerun of " + id,
+	})
+
+	if wantsJSON(r) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusAccepted)
+		body := map[string]string{"id": view.ID, "status": view.Status}
+		if view.Error != nil {
+			body["error"] = view.Error.Error()
+		}
+		if err := json.NewEncoder(w).Encode(body); err != nil {
+			h.logger.Error("failed to encode rerun result", "error", err)
+		}
+		return
+	}
+
+	sse, ok := h.streams.Start(w, r)
+	if !ok {
+		return
+	}
+	if view.Status == jobs.StatusRejected {
+		sseutil.Toast(sse, views.ToastWarning, "Rerun rejected: "+view.Error.Error())
+		return
+	}
+	sseutil.Toast(sse, views.ToastInfo, "Job rerun as "+view.ID)
+}
+
+// JobResult downloads a completed job's output as an attachment. Range
+// requests are supported, so large results can be resumed.
+func (h *Handlers) JobResult(w http.ResponseWriter, r *http.Request) {
+	job, ok := h.jobHub.Get(r.PathValue("id"))
+	if !ok {
+		apiError(w, r, http.StatusNotFound, errCodeNotFound, "job not found")
+		return
+	}
+
+	res, err := job.OpenResult()
+	switch {
+	case errors.Is(err, jobs.ErrResultNotReady):
+		apiError(w, r, http.StatusConflict, errCodeConflict, "job has not completed")
+		return
+	case errors.Is(err, jobs.ErrNoResult):
+		apiError(w, r, http.StatusNotFound, errCodeNotFound, "job has no result")
+		return
+	case err != nil:
+		h.logger.Error("failed to open job result", "job_id", job.ID, "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	defer res.Close()
+
+	if res.ContentType != "" {
+		w.Header().Set("Content-Type", res.ContentType)
+	}
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": res.Filename}))
+	http.ServeContent(w, r, res.Filename, res.ModTime, res)
+}
+
+// CancelAllJobs cancels every pending, running or paused job. Browsers get
+// a toast; clients asking for JSON get {"cancelled": n}.
+func (h *Handlers) CancelAllJobs(w http.ResponseWriter, r *http.Request) {
+	n := h.jobHub.CancelAll()
+	audit.Record(audit.Event{
+		Action: "jobs.cancel_all",
+		Actor:  clientID(r),
+		Detail: fmt.Sprintf("cancelled %d jobs", n),
+	})
+
+	if wantsJSON(r) {
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(map[string]int{"cancelled": n}); err != nil {
+			h.logger.Error("failed to encode cancel-all result", "error", err)
+		}
+		return
+	}
+
+	sse, ok := h.streams.Start(w, r)
+	if !ok {
+		return
+	}
+	sseutil.Toast(sse, views.ToastWarning, fmt.Sprintf("Cancelled %d jobs", n))
+}
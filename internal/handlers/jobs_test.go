@@ -0,0 +1,160 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ankit-lilly/go-datastar-daisyui-template/internal/jobs"
+)
+
+// blockingJob submits a job that runs until it is cancelled and waits for
+// it to start.
+func blockingJob(t *testing.T, hub *jobs.Hub) *jobs.Job {
+	t.Helper()
+	started := make(chan struct{})
+	job := hub.NewJob("block", func(j *jobs.Job) error {
+		close(started)
+		<-j.Context().Done()
+		return j.Context().Err()
+	})
+	if err := hub.Submit(job); err != nil {
+		t.Fatalf("Submit: %v", err)
+	}
+	select {
+	case <-started:
+	case <-time.After(5 * time.Second):
+		t.Fatal("job did not start")
+	}
+	return job
+}
+
+// waitDone drains job's updates until the terminal one.
+func waitDone(t *testing.T, job *jobs.Job) jobs.JobUpdate {
+	t.Helper()
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case u, ok := <-job.Updates():
+			if !ok || u.Done {
+				return u
+			}
+		case <-timeout:
+			t.Fatalf("job %s did not finish", job.ID)
+		}
+	}
+}
+
+func TestCancelAllJobsJSON(t *testing.T) {
+	h, hub := newTestHandlers(t)
+	a, b := blockingJob(t, hub), blockingJob(t, hub)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/jobs/cancel-all", nil)
+	req.Header.Set("Accept", "application/json")
+	rec := httptest.NewRecorder()
+	h.CancelAllJobs(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status %d, want 200", rec.Code)
+	}
+	var body map[string]int
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("invalid JSON %q: %v", rec.Body, err)
+	}
+	if body["cancelled"] != 2 {
+		t.Fatalf("cancelled = %d, want 2", body["cancelled"])
+	}
+	for _, job := range []*jobs.Job{a, b} {
+		waitDone(t, job)
+		if status := job.Snapshot().Status; status != jobs.StatusFailed {
+			t.Errorf("job %s: status %q, want %q", job.ID, status, jobs.StatusFailed)
+		}
+	}
+}
+
+func TestCancelAllJobsSSE(t *testing.T) {
+	h, hub := newTestHandlers(t)
+	job := blockingJob(t, hub)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/jobs/cancel-all", nil)
+	req.Header.Set("Datastar-Request", "true")
+	rec := httptest.NewRecorder()
+	h.CancelAllJobs(rec, req)
+
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/event-stream") {
+		t.Fatalf("Content-Type %q, want an event stream", ct)
+	}
+	if !strings.Contains(rec.Body.String(), "Cancelled 1 jobs") {
+		t.Fatalf("body %q lacks the toast", rec.Body)
+	}
+	waitDone(t, job)
+}
+
+func TestStartJobQueueFull(t *testing.T) {
+	h, hub := newTestHandlers(t, jobs.WithWorkers(1))
+	blockingJob(t, hub)
+	for {
+		err := hub.Submit(hub.NewJob("filler", func(*jobs.Job) error { return nil }))
+		if errors.Is(err, jobs.ErrQueueFull) {
+			break
+		}
+		if err != nil {
+			t.Fatalf("filler: %v", err)
+		}
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/api/job/start", nil)
+	req.Header.Set("Datastar-Request", "true")
+	rec := httptest.NewRecorder()
+	h.StartJob(rec, req)
+
+	body := rec.Body.String()
+	for _, want := range []string{`"jobStatus":"rejected"`, "Server busy, try again"} {
+		if !strings.Contains(body, want) {
+			t.Errorf("body lacks %q:\n%s", want, body)
+		}
+	}
+}
+
+func TestJobsListJSON(t *testing.T) {
+	h, hub := newTestHandlers(t)
+	job := blockingJob(t, hub)
+	job.SetProgress(40)
+
+	rec := httptest.NewRecorder()
+	h.JobsListJSON(rec, httptest.NewRequest(http.MethodGet, "/api/jobs.json", nil))
+
+	if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+		t.Fatalf("Content-Type %q, want application/json", ct)
+	}
+	var items []map[string]any
+	if err := json.Unmarshal(rec.Body.Bytes(), &items); err != nil {
+		t.Fatalf("invalid JSON %q: %v", rec.Body, err)
+	}
+	if len(items) != 1 {
+		t.Fatalf("%d items, want 1: %s", len(items), rec.Body)
+	}
+
+	view := job.Snapshot()
+	want := map[string]any{
+		"id":        view.ID,
+		"name":      view.Name,
+		"status":    jobs.StatusRunning,
+		"progress":  float64(40),
+		"createdAt": view.CreatedAt.Format(time.RFC3339Nano),
+	}
+	if len(items[0]) != len(want) {
+		t.Errorf("fields = %v, want exactly %v", items[0], want)
+	}
+	for key, value := range want {
+		if items[0][key] != value {
+			t.Errorf("%s = %v, want %v", key, items[0][key], value)
+		}
+	}
+	job.Cancel()
+	waitDone(t, job)
+}
@@ -0,0 +1,74 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"sync/atomic"
+
+	"github.com/ankit-lilly/go-datastar-daisyui-template/internal/session"
+)
+
+// optimisticRejectEvery is how often the optimistic counter turns an
+// increment down, so the page has something to reconcile.
+const optimisticRejectEvery = 5
+
+// OptimisticCounter sends the optimistic counter's confirmed value.
+func (h *Handlers) OptimisticCounter(w http.ResponseWriter, r *http.Request) {
+	s, ok := requireSession(w, r)
+	if !ok {
+		return
+	}
+	count, _ := optimisticOutcome(h.optimisticAttempts(s).Load())
+
+	sse, ok := h.streams.Start(w, r)
+	if !ok {
+		return
+	}
+	sse.MarshalAndPatchSignals(map[string]any{"optimisticCount": count, "optimisticStatus": "", "optimisticNote": ""})
+}
+
+// OptimisticIncrement is the server's side of an increment the page has
+// already shown. It answers with the authoritative count, which confirms
+// the page's value or, for every optimisticRejectEvery-th attempt, corrects
+// it. The count is absolute, so the page ends up right even when Datastar
+// cancels a click's request in favour of the next.
+func (h *Handlers) OptimisticIncrement(w http.ResponseWriter, r *http.Request) {
+	s, ok := requireSession(w, r)
+	if !ok {
+		return
+	}
+	if h.clientGone(r) {
+		return
+	}
+	sse, ok := h.streams.Start(w, r)
+	if !ok {
+		return
+	}
+
+	attempt := h.optimisticAttempts(s).Add(1)
+	count, rejected := optimisticOutcome(attempt)
+	status, note := "confirmed", ""
+	if rejected {
+		status = "corrected"
+		note = fmt.Sprintf("The server rejected increment #%d, so the count was set back to %d.", attempt, count)
+	}
+	sse.MarshalAndPatchSignals(map[string]any{"optimisticCount": count, "optimisticStatus": status, "optimisticNote": note})
+}
+
+// optimisticAttempts returns the session's increment attempts. They share
+// the counters store, and so its expiry, with the session's counter.
+func (h *Handlers) optimisticAttempts(s *session.Session) *atomic.Int64 {
+	key := s.ID + "/optimistic"
+	c, loaded := h.counters.LoadOrStore(key, new(atomic.Int64))
+	if loaded {
+		h.counters.Touch(key)
+	}
+	return c
+}
+
+// optimisticOutcome returns the count after attempt increments, every
+// optimisticRejectEvery-th of which was rejected, and whether attempt itself
+// was.
+func optimisticOutcome(attempt int64) (count int64, rejected bool) {
+	return attempt - attempt/optimisticRejectEvery, attempt > 0 && attempt%optimisticRejectEvery == 0
+}
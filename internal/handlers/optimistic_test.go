@@ -0,0 +1,26 @@
+package handlers
+
+import "testing"
+
+func TestOptimisticOutcome(t *testing.T) {
+	tests := []struct {
+		attempt      int64
+		wantCount    int64
+		wantRejected bool
+	}{
+		{0, 0, false},
+		{1, 1, false},
+		{4, 4, false},
+		{5, 4, true},
+		{6, 5, false},
+		{9, 8, false},
+		{10, 8, true},
+		{11, 9, false},
+	}
+	for _, tt := range tests {
+		count, rejected := optimisticOutcome(tt.attempt)
+		if count != tt.wantCount || rejected != tt.wantRejected {
+			t.Errorf("optimisticOutcome(%d) = %d, %v; want %d, %v", tt.attempt, count, rejected, tt.wantCount, tt.wantRejected)
+		}
+	}
+}
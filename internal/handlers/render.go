@@ -0,0 +1,99 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"time"
+
+	"github.com/a-h/templ"
+	"github.com/ankit-lilly/go-datastar-daisyui-template/internal/config"
+	"github.com/ankit-lilly/go-datastar-daisyui-template/internal/servertiming"
+	"github.com/ankit-lilly/go-datastar-daisyui-template/internal/views"
+	"github.com/starfederation/datastar-go/datastar"
+)
+
+// renderPage renders a full page into a buffer under the configured render
+// timeout and only writes it to w once rendering has finished, so a slow or
+// hung render never leaves the client with a partial document. On timeout
+// the client gets a 503 error page. The render time is reported in the
+// Server-Timing header.
+func (h *Handlers) renderPage(w http.ResponseWriter, r *http.Request, component templ.Component) {
+	timeout := config.Current().RenderTimeout
+	ctx, cancel := context.WithTimeout(r.Context(), timeout)
+	defer cancel()
+
+	var buf bytes.Buffer
+	done := make(chan error, 1)
+	start := time.Now()
+	go func() {
+		done <- component.Render(ctx, &buf)
+	}()
+
+	select {
+	case err := <-done:
+		servertiming.Since(r.Context(), "render", start)
+		if err != nil {
+			h.logger.Error("template render error", "path", r.URL.Path, "error", err)
+			h.renderError(w, r, http.StatusInternalServerError, "Something went wrong rendering this page.")
+			return
+		}
+	case <-ctx.Done():
+		if r.Context().Err() != nil {
+			return
+		}
+		h.logger.Error("template render timed out", "path", r.URL.Path, "timeout", timeout)
+		h.renderError(w, r, http.StatusServiceUnavailable, "This page took too long to render. Please try again.")
+		return
+	}
+
+	h.serveConditional(w, r, buf.Bytes())
+}
+
+// serveConditional writes a rendered page with an ETag built from its
+// content hash and the theme, and a Last-Modified of the server start, and
+// answers 304 to a matching If-None-Match (which takes precedence over
+// If-Modified-Since). The page embeds the visitor's CSRF token, so it may
+// only be cached privately and is revalidated on every load.
+//
+// A page carrying a CSP nonce is never cached: a 304 would keep the old
+// nonce while the new response's header allows only a fresh one.
+func (h *Handlers) serveConditional(w http.ResponseWriter, r *http.Request, body []byte) {
+	header := w.Header()
+	header.Set("Content-Type", "text/html; charset=utf-8")
+	if views.Nonce(r.Context()) != "" {
+		header.Set("Cache-Control", "no-store")
+		w.Write(body)
+		return
+	}
+
+	sum := sha256.Sum256(body)
+	etag := `"` + hex.EncodeToString(sum[:8]) + "-" + views.Theme(r.Context()) + `"`
+	header.Set("ETag", etag)
+	header.Set("Cache-Control", "private, no-cache")
+	header.Add("Vary", "Cookie")
+	http.ServeContent(w, r, "", h.started, bytes.NewReader(body))
+}
+
+// renderError writes a DaisyUI error page with the given status.
+func (h *Handlers) renderError(w http.ResponseWriter, r *http.Request, status int, message string) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+	if err := views.ErrorPage(status, message).Render(r.Context(), w); err != nil {
+		h.logger.Error("error page render failed", "error", err)
+	}
+}
+
+// patch renders component into a buffer and patches it only if rendering
+// succeeded, so a failing template never sends a partial fragment.
+func (h *Handlers) patch(sse *datastar.ServerSentEventGenerator, component templ.Component, opts ...datastar.PatchElementOption) {
+	if err := sse.PatchElementTempl(component, opts...); err != nil {
+		if sse.IsClosed() {
+			h.logger.Debug("client disconnected before patch", "error", err)
+			return
+		}
+		h.logger.Error("fragment patch failed", "error", err)
+	}
+}
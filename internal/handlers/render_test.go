@@ -0,0 +1,106 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/a-h/templ"
+	"github.com/ankit-lilly/go-datastar-daisyui-template/internal/config"
+	"github.com/ankit-lilly/go-datastar-daisyui-template/internal/views"
+	"github.com/starfederation/datastar-go/datastar"
+)
+
+func TestServeConditional(t *testing.T) {
+	h, _ := newTestHandlers(t)
+	body := []byte("<p>page</p>")
+
+	rec := httptest.NewRecorder()
+	h.serveConditional(rec, httptest.NewRequest(http.MethodGet, "/", nil), body)
+	etag := rec.Header().Get("ETag")
+	if etag == "" {
+		t.Fatal("no ETag on a page without a nonce")
+	}
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("If-None-Match", etag)
+	rec = httptest.NewRecorder()
+	h.serveConditional(rec, r, body)
+	if rec.Code != http.StatusNotModified {
+		t.Fatalf("status %d, want 304", rec.Code)
+	}
+}
+
+func TestServeConditionalNonceNotCached(t *testing.T) {
+	h, _ := newTestHandlers(t)
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("If-None-Match", "*")
+	r = r.WithContext(views.WithNonce(r.Context(), "abc"))
+	rec := httptest.NewRecorder()
+	h.serveConditional(rec, r, []byte("<p>page</p>"))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status %d, want 200", rec.Code)
+	}
+	if got := rec.Header().Get("Cache-Control"); got != "no-store" {
+		t.Fatalf("Cache-Control %q, want no-store", got)
+	}
+	if got := rec.Header().Get("ETag"); got != "" {
+		t.Fatalf("ETag %q on a nonce-bearing page", got)
+	}
+}
+
+// partialFailure writes part of a page, then fails.
+var partialFailure = templ.ComponentFunc(func(_ context.Context, w io.Writer) error {
+	io.WriteString(w, "<p>half a page")
+	return errors.New("template exploded")
+})
+
+func TestRenderPageFailsCleanly(t *testing.T) {
+	h, _ := newTestHandlers(t)
+	config.Set(&config.Config{RenderTimeout: time.Second})
+
+	rec := httptest.NewRecorder()
+	h.renderPage(rec, httptest.NewRequest(http.MethodGet, "/", nil), partialFailure)
+	if rec.Code != http.StatusInternalServerError {
+		t.Fatalf("status %d, want 500", rec.Code)
+	}
+	if body := rec.Body.String(); strings.Contains(body, "half a page") {
+		t.Fatalf("partial output reached the client:\n%s", body)
+	}
+	if !strings.Contains(rec.Body.String(), "Something went wrong") {
+		t.Fatalf("body is not the error page:\n%s", rec.Body)
+	}
+
+	// A fragment that fails is not patched at all.
+	rec = httptest.NewRecorder()
+	h.patch(datastar.NewSSE(rec, httptest.NewRequest(http.MethodGet, "/", nil)), partialFailure)
+	if body := rec.Body.String(); strings.Contains(body, "half a page") || strings.Contains(body, "datastar-patch-elements") {
+		t.Fatalf("failed fragment was patched:\n%s", body)
+	}
+}
+
+func TestRenderPageTimeout(t *testing.T) {
+	h, _ := newTestHandlers(t)
+	config.Set(&config.Config{RenderTimeout: 20 * time.Millisecond})
+	hung := templ.ComponentFunc(func(ctx context.Context, w io.Writer) error {
+		io.WriteString(w, "<p>still going")
+		<-ctx.Done()
+		return ctx.Err()
+	})
+
+	rec := httptest.NewRecorder()
+	h.renderPage(rec, httptest.NewRequest(http.MethodGet, "/", nil), hung)
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("status %d, want 503", rec.Code)
+	}
+	if strings.Contains(rec.Body.String(), "still going") {
+		t.Fatalf("partial output reached the client:\n%s", rec.Body)
+	}
+}
@@ -0,0 +1,74 @@
+package handlers
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ankit-lilly/go-datastar-daisyui-template/internal/lifecycle"
+	"github.com/ankit-lilly/go-datastar-daisyui-template/internal/middleware"
+)
+
+// TestRunJobStreamEnd checks that a RunJob job is cancelled when its client
+// leaves, but not when the server closes the stream to shut down or to
+// enter maintenance.
+func TestRunJobStreamEnd(t *testing.T) {
+	for _, tc := range []struct {
+		name          string
+		end           func(cancelRequests context.CancelCauseFunc, maintenance *middleware.MaintenanceMode)
+		wantCancelled bool
+	}{
+		{"client gone", func(cancel context.CancelCauseFunc, _ *middleware.MaintenanceMode) { cancel(nil) }, true},
+		{"server shutdown", func(cancel context.CancelCauseFunc, _ *middleware.MaintenanceMode) { cancel(lifecycle.ErrShuttingDown) }, false},
+		{"maintenance on", func(_ context.CancelCauseFunc, m *middleware.MaintenanceMode) { m.Set(true) }, false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			h, hub := newTestHandlers(t)
+			maintenance := middleware.NewMaintenanceMode()
+			baseCtx, cancelRequests := context.WithCancelCause(context.Background())
+			srv := httptest.NewUnstartedServer(maintenance.Middleware()(http.HandlerFunc(h.RunJob)))
+			srv.Config.BaseContext = func(net.Listener) context.Context { return baseCtx }
+			srv.Start()
+			defer srv.Close()
+
+			req, _ := http.NewRequest(http.MethodPost, srv.URL, nil)
+			req.Header.Set("Datastar-Request", "true")
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+			waitForEvent(t, bufio.NewReader(resp.Body), `"jobStatus":"running"`)
+
+			tc.end(cancelRequests, maintenance)
+			// The stream ends once the handler has returned.
+			io.Copy(io.Discard, resp.Body)
+
+			all := hub.ListSorted()
+			if len(all) != 1 {
+				t.Fatalf("%d jobs, want 1", len(all))
+			}
+			if cancelled := all[0].Context().Err() != nil; cancelled != tc.wantCancelled {
+				t.Fatalf("job cancelled = %v, want %v", cancelled, tc.wantCancelled)
+			}
+		})
+	}
+}
+
+func waitForEvent(t *testing.T, body *bufio.Reader, substr string) {
+	t.Helper()
+	for {
+		line, err := body.ReadString('\n')
+		if err != nil {
+			t.Fatalf("stream ended before %s: %v", substr, err)
+		}
+		if strings.Contains(line, substr) {
+			return
+		}
+	}
+}
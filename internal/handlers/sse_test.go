@@ -0,0 +1,34 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// TestPlainRequestToSSEHandler checks that handlers answering with a short
+// event stream turn away a request that didn't ask for one, before doing
+// any work.
+func TestPlainRequestToSSEHandler(t *testing.T) {
+	h, _ := newTestHandlers(t)
+
+	rec := httptest.NewRecorder()
+	h.Increment(rec, httptest.NewRequest(http.MethodPost, "/api/increment", nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("plain POST: status %d, want 400", rec.Code)
+	}
+	if got := h.counter.Load(); got != 0 {
+		t.Fatalf("counter %d after a rejected request, want 0", got)
+	}
+
+	r := httptest.NewRequest(http.MethodPost, "/api/increment", nil)
+	r.Header.Set("Datastar-Request", "true")
+	rec = httptest.NewRecorder()
+	h.Increment(rec, r)
+	if ct := rec.Header().Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("Datastar POST: Content-Type %q, want text/event-stream", ct)
+	}
+	if got := h.counter.Load(); got != 1 {
+		t.Fatalf("counter %d, want 1", got)
+	}
+}
@@ -0,0 +1,145 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/ankit-lilly/go-datastar-daisyui-template/internal/session"
+	"github.com/ankit-lilly/go-datastar-daisyui-template/internal/signals"
+	"github.com/ankit-lilly/go-datastar-daisyui-template/internal/views"
+	"github.com/starfederation/datastar-go/datastar"
+)
+
+const wizardSessionKey = "wizard"
+
+// WizardStep renders the step named by the wizardStep signal. Steps past
+// the first incomplete one can't be opened out of order; that step is shown
+// instead with a notice. The form signals are refilled from the session so
+// going back shows what was entered.
+func (h *Handlers) WizardStep(w http.ResponseWriter, r *http.Request) {
+	req, err := signals.Decode[struct {
+		Step int `json:"wizardStep" validate:"min=1,max=3"`
+	}](r)
+	if err != nil {
+		signals.BadRequest(w, err)
+		return
+	}
+	s, ok := requireSession(w, r)
+	if !ok {
+		return
+	}
+
+	data := wizardData(s)
+	state := views.WizardState{Step: req.Step, Data: data}
+	if next := data.NextStep(); state.Step > next {
+		state.Step = next
+		state.Notice = "Please complete this step first."
+	}
+
+	sse, ok := h.streams.Start(w, r)
+	if !ok {
+		return
+	}
+	sse.MarshalAndPatchSignals(map[string]any{"name": data.Name, "email": data.Email, "plan": data.Plan})
+	h.patchWizard(sse, state)
+}
+
+// WizardAccount validates and stores the account step, then shows the plan
+// step. Invalid input re-renders the account step with the errors.
+func (h *Handlers) WizardAccount(w http.ResponseWriter, r *http.Request) {
+	req, err := signals.Decode[struct {
+		Name  string `json:"name" validate:"notblank,max=100"`
+		Email string `json:"email" validate:"required,email,max=254"`
+	}](r)
+	s, ok := requireSession(w, r)
+	if !ok {
+		return
+	}
+	data := wizardData(s)
+
+	sse, ok := h.streams.Start(w, r)
+	if !ok {
+		return
+	}
+	if err != nil {
+		h.patchWizard(sse, views.WizardState{Step: views.WizardAccount, Data: data, Notice: signals.Message(err)})
+		return
+	}
+
+	data.Name = strings.TrimSpace(req.Name)
+	data.Email = strings.TrimSpace(req.Email)
+	s.Set(wizardSessionKey, data)
+	h.patchWizard(sse, views.WizardState{Step: views.WizardPlan, Data: data})
+}
+
+// WizardPlan validates and stores the plan step, then shows the summary.
+func (h *Handlers) WizardPlan(w http.ResponseWriter, r *http.Request) {
+	req, err := signals.Decode[struct {
+		Plan string `json:"plan" validate:"required"`
+	}](r)
+	if err == nil && !slices.Contains(views.WizardPlans, req.Plan) {
+		err = fmt.Errorf("%w: plan must be one of %s", signals.ErrInvalid, strings.Join(views.WizardPlans, ", "))
+	}
+	s, ok := requireSession(w, r)
+	if !ok {
+		return
+	}
+	data := wizardData(s)
+
+	sse, ok := h.streams.Start(w, r)
+	if !ok {
+		return
+	}
+	if data.NextStep() < views.WizardPlan {
+		h.patchWizard(sse, views.WizardState{Step: data.NextStep(), Data: data, Notice: "Please complete this step first."})
+		return
+	}
+	if err != nil {
+		h.patchWizard(sse, views.WizardState{Step: views.WizardPlan, Data: data, Notice: signals.Message(err)})
+		return
+	}
+
+	data.Plan = req.Plan
+	s.Set(wizardSessionKey, data)
+	h.patchWizard(sse, views.WizardState{Step: views.WizardSummary, Data: data})
+}
+
+// WizardReset forgets the wizard's data and returns to the first step.
+func (h *Handlers) WizardReset(w http.ResponseWriter, r *http.Request) {
+	s, ok := requireSession(w, r)
+	if !ok {
+		return
+	}
+	s.Delete(wizardSessionKey)
+
+	sse, ok := h.streams.Start(w, r)
+	if !ok {
+		return
+	}
+	sse.MarshalAndPatchSignals(map[string]any{"name": "", "email": "", "plan": ""})
+	h.patchWizard(sse, views.WizardState{Step: views.WizardAccount})
+}
+
+func (h *Handlers) patchWizard(sse *datastar.ServerSentEventGenerator, state views.WizardState) {
+	sse.MarshalAndPatchSignals(map[string]any{"wizardStep": state.Step})
+	h.patch(sse, views.WizardStep(state))
+}
+
+func wizardData(s *session.Session) views.WizardData {
+	data, _ := s.Get(wizardSessionKey)
+	d, _ := data.(views.WizardData)
+	return d
+}
+
+// requireSession returns the request's session, answering 500 if the
+// session middleware isn't installed.
+func requireSession(w http.ResponseWriter, r *http.Request) (*session.Session, bool) {
+	s := session.FromContext(r.Context())
+	if s == nil {
+		http.Error(w, "Internal Server Error - no session", http.StatusInternalServerError)
+		return nil, false
+	}
+	return s, true
+}
@@ -0,0 +1,46 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestWizardRejectsOutOfOrderSteps(t *testing.T) {
+	h, _ := newTestHandlers(t)
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/wizard/step", h.WizardStep)
+	mux.HandleFunc("POST /api/wizard/account", h.WizardAccount)
+	mux.HandleFunc("POST /api/wizard/plan", h.WizardPlan)
+	srv := newSessionServer(t, mux)
+	client := newSessionClient(t)
+
+	const notice = "Please complete this step first."
+	wantStep := func(t *testing.T, body, step string) {
+		t.Helper()
+		if !strings.Contains(body, `"wizardStep":`+step) {
+			t.Errorf("body does not move to step %s:\n%s", step, body)
+		}
+	}
+
+	// Neither opening the summary nor submitting a plan skips the account.
+	_, body := datastarDo(t, client, http.MethodGet, srv.URL+"/api/wizard/step", map[string]any{"wizardStep": 3})
+	wantStep(t, body, "1")
+	if !strings.Contains(body, notice) {
+		t.Errorf("body lacks the notice:\n%s", body)
+	}
+	_, body = datastarDo(t, client, http.MethodPost, srv.URL+"/api/wizard/plan", map[string]any{"plan": "pro"})
+	wantStep(t, body, "1")
+	if !strings.Contains(body, notice) {
+		t.Errorf("body lacks the notice:\n%s", body)
+	}
+
+	// Once the account is done the plan step opens, but not the summary.
+	_, body = datastarDo(t, client, http.MethodPost, srv.URL+"/api/wizard/account", map[string]any{"name": "Ada", "email": "ada@example.com"})
+	wantStep(t, body, "2")
+	_, body = datastarDo(t, client, http.MethodGet, srv.URL+"/api/wizard/step", map[string]any{"wizardStep": 3})
+	wantStep(t, body, "2")
+	if !strings.Contains(body, notice) {
+		t.Errorf("body lacks the notice:\n%s", body)
+	}
+}
@@ -0,0 +1,27 @@
+package jobs
+
+import "github.com/ankit-lilly/go-datastar-daisyui-template/internal/audit"
+
+// auditJob records action for job in the audit log, with the job's Owner
+// as the actor. Internal jobs are not audited.
+func auditJob(job *Job, action string) {
+	if job.internal {
+		return
+	}
+	job.mu.RLock()
+	e := audit.Event{
+		Action:  action,
+		Actor:   job.Owner,
+		JobID:   job.ID,
+		JobName: job.Name,
+		Status:  job.Status,
+	}
+	if job.Error != nil {
+		e.Error = job.Error.Error()
+	}
+	job.mu.RUnlock()
+	if e.Actor == "" {
+		e.Actor = "system"
+	}
+	audit.Record(e)
+}
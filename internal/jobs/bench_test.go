@@ -0,0 +1,45 @@
+package jobs
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+// benchmarkSubmit submits b.N short jobs to a hub built with opts and waits
+// for all of them to finish.
+func benchmarkSubmit(b *testing.B, opts ...Option) {
+	opts = append(opts, WithQueuePolicy(QueueBlock, time.Minute), WithProgressLogStep(0))
+	h := newTestHub(b, opts...)
+	work := func(j *Job) error {
+		j.SetProgress(50)
+		return nil
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	submitted := make([]*Job, 0, b.N)
+	for range b.N {
+		job := h.NewJob("bench", work)
+		if err := h.Submit(job); err != nil {
+			b.Fatalf("Submit: %v", err)
+		}
+		submitted = append(submitted, job)
+	}
+	for _, job := range submitted {
+		for range job.Updates() {
+		}
+	}
+}
+
+func BenchmarkSubmitSpawnPerJob(b *testing.B) {
+	benchmarkSubmit(b)
+}
+
+func BenchmarkSubmitWorkerPool(b *testing.B) {
+	for _, n := range []int{1, 4, 16} {
+		b.Run(fmt.Sprintf("workers=%d", n), func(b *testing.B) {
+			benchmarkSubmit(b, WithWorkers(n))
+		})
+	}
+}
@@ -0,0 +1,161 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+)
+
+var (
+	ErrDependencyCycle  = errors.New("jobs: dependency cycle")
+	ErrDependencyFailed = errors.New("jobs: dependency did not complete")
+)
+
+// SubmitAfterJob submits job to run once every job in dependsOn has
+// completed successfully. Until then it stays pending. If a dependency
+// fails, is interrupted or is itself skipped, job is marked StatusSkipped
+// without running, and so are its own dependents.
+//
+// Unknown dependency IDs return ErrJobNotFound, cycles return
+// ErrDependencyCycle and resubmissions ErrDuplicateJob; in all three cases
+// job is not submitted. If job's Owner is
+// at its cap, or job can start right away but the queue is full, it is
+// rejected as with Submit.
+func (h *Hub) SubmitAfterJob(job *Job, dependsOn ...string) error {
+	h.mu.Lock()
+	if h.submittedLocked(job) {
+		h.mu.Unlock()
+		return h.rejectDuplicate(job)
+	}
+	for _, id := range dependsOn {
+		if id == job.ID || h.reaches(id, job.ID) {
+			h.mu.Unlock()
+			return fmt.Errorf("%w: %s depends on %s", ErrDependencyCycle, job.ID, id)
+		}
+	}
+
+	waiting := make(map[string]bool)
+	failed := ""
+	for _, id := range dependsOn {
+		status, ok := h.statusLocked(id)
+		if !ok {
+			h.mu.Unlock()
+			return fmt.Errorf("%w: %s", ErrJobNotFound, id)
+		}
+		switch status {
+		case StatusCompleted:
+		case StatusPending, StatusRunning, StatusPaused:
+			waiting[id] = true
+		default:
+			failed = id
+		}
+	}
+
+	h.jobs[job.ID] = job
+	if !h.claimOwnerLocked(job) {
+		h.mu.Unlock()
+		h.persist(job)
+		auditJob(job, "job.submitted")
+		return h.rejectOwner(job)
+	}
+	h.dependsOn[job.ID] = slices.Clone(dependsOn)
+	if failed == "" && len(waiting) > 0 {
+		h.waitingOn[job.ID] = waiting
+		for id := range waiting {
+			h.dependents[id] = append(h.dependents[id], job.ID)
+		}
+	}
+	h.mu.Unlock()
+
+	h.persist(job)
+	auditJob(job, "job.submitted")
+
+	switch {
+	case failed != "":
+		h.skip(job, failed)
+	case len(waiting) == 0:
+		return h.enqueue(context.Background(), job)
+	default:
+		h.logger.Info("job waiting on dependencies", "job_id", job.ID, "depends_on", dependsOn)
+	}
+	return nil
+}
+
+// statusLocked returns the status of a live or stored job. h.mu must be
+// held.
+func (h *Hub) statusLocked(id string) (string, bool) {
+	if job, ok := h.jobs[id]; ok {
+		return job.Snapshot().Status, true
+	}
+	job, err := h.store.Load(id)
+	if err != nil {
+		return "", false
+	}
+	return job.Snapshot().Status, true
+}
+
+// reaches reports whether target is a transitive dependency of from. h.mu
+// must be held.
+func (h *Hub) reaches(from, target string) bool {
+	seen := make(map[string]bool)
+	stack := []string{from}
+	for len(stack) > 0 {
+		id := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if id == target {
+			return true
+		}
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		stack = append(stack, h.dependsOn[id]...)
+	}
+	return false
+}
+
+// release is called when job id finishes. Dependents whose last dependency
+// this was are queued; if id did not succeed, its dependents are skipped.
+func (h *Hub) release(id string, succeeded bool) {
+	h.mu.Lock()
+	var ready, skipped []*Job
+	for _, depID := range h.dependents[id] {
+		waiting, ok := h.waitingOn[depID]
+		if !ok {
+			continue
+		}
+		if !succeeded {
+			delete(h.waitingOn, depID)
+			skipped = append(skipped, h.jobs[depID])
+			continue
+		}
+		delete(waiting, id)
+		if len(waiting) == 0 {
+			delete(h.waitingOn, depID)
+			ready = append(ready, h.jobs[depID])
+		}
+	}
+	delete(h.dependents, id)
+	h.mu.Unlock()
+
+	for _, job := range ready {
+		// A rejected job is finished by enqueue and releases its own
+		// dependents. Under QueueBlock the wait happens off this goroutine,
+		// which may be a worker the queue is waiting on.
+		if h.queuePolicy == QueueBlock {
+			go h.enqueue(context.Background(), job)
+			continue
+		}
+		h.enqueue(context.Background(), job)
+	}
+	for _, job := range skipped {
+		h.skip(job, id)
+	}
+}
+
+// skip finishes a held job without running it because dependency failed.
+func (h *Hub) skip(job *Job, dependency string) {
+	h.logger.Warn("job skipped", "job_id", job.ID, "dependency", dependency)
+	h.finishUnrun(job, StatusSkipped, fmt.Errorf("%w: %s", ErrDependencyFailed, dependency))
+}
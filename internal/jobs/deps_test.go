@@ -0,0 +1,111 @@
+package jobs
+
+import (
+	"errors"
+	"slices"
+	"sync"
+	"testing"
+)
+
+func TestSubmitAfterJobRunsInOrder(t *testing.T) {
+	h := newTestHub(t, WithWorkers(3))
+
+	var mu sync.Mutex
+	var order []string
+	release := make(chan struct{})
+	record := func(name string, wait bool) JobFunc {
+		return func(j *Job) error {
+			if wait {
+				<-release
+			}
+			mu.Lock()
+			order = append(order, name)
+			mu.Unlock()
+			return nil
+		}
+	}
+
+	a := h.NewJob("a", record("a", true))
+	b := h.NewJob("b", record("b", false))
+	c := h.NewJob("c", record("c", false))
+	if err := h.Submit(a); err != nil {
+		t.Fatalf("submit a: %v", err)
+	}
+	if err := h.SubmitAfterJob(b, a.ID); err != nil {
+		t.Fatalf("submit b: %v", err)
+	}
+	if err := h.SubmitAfterJob(c, b.ID); err != nil {
+		t.Fatalf("submit c: %v", err)
+	}
+
+	for _, job := range []*Job{b, c} {
+		if status := job.Snapshot().Status; status != StatusPending {
+			t.Fatalf("%s: status %q before a finished, want pending", job.Name, status)
+		}
+	}
+	close(release)
+
+	for _, job := range []*Job{a, b, c} {
+		if u := waitFinal(t, job); u.Error != nil {
+			t.Fatalf("%s failed: %v", job.Name, u.Error)
+		}
+	}
+	if want := []string{"a", "b", "c"}; !slices.Equal(order, want) {
+		t.Fatalf("order = %v, want %v", order, want)
+	}
+}
+
+func TestSubmitAfterJobSkipsOnFailure(t *testing.T) {
+	h := newTestHub(t)
+	errBoom := errors.New("boom")
+	a := h.NewJob("a", func(*Job) error { return errBoom })
+	ran := false
+	b := h.NewJob("b", func(*Job) error { ran = true; return nil })
+
+	if err := h.Submit(a); err != nil {
+		t.Fatalf("submit a: %v", err)
+	}
+	if err := h.SubmitAfterJob(b, a.ID); err != nil {
+		t.Fatalf("submit b: %v", err)
+	}
+	waitFinal(t, a)
+	u := waitFinal(t, b)
+	if !errors.Is(u.Error, ErrDependencyFailed) {
+		t.Fatalf("b error = %v, want ErrDependencyFailed", u.Error)
+	}
+	if status := b.Snapshot().Status; status != StatusSkipped || ran {
+		t.Fatalf("b: status %q, ran %v; want skipped without running", status, ran)
+	}
+}
+
+func TestSubmitAfterJobRejectsCycles(t *testing.T) {
+	h := newTestHub(t)
+
+	self := h.NewJob("self", func(*Job) error { return nil })
+	if err := h.SubmitAfterJob(self, self.ID); !errors.Is(err, ErrDependencyCycle) {
+		t.Fatalf("self dependency: err = %v, want ErrDependencyCycle", err)
+	}
+
+	// b depends on a; once a has finished, a new job reusing a's ID may not
+	// depend on b.
+	a := h.NewJob("a", func(*Job) error { return nil })
+	if err := h.Submit(a); err != nil {
+		t.Fatalf("submit a: %v", err)
+	}
+	waitFinal(t, a)
+	b := h.NewJob("b", func(*Job) error { return nil })
+	if err := h.SubmitAfterJob(b, a.ID); err != nil {
+		t.Fatalf("submit b: %v", err)
+	}
+	waitFinal(t, b)
+
+	again := h.NewJob("a again", func(*Job) error { return nil })
+	again.ID = a.ID
+	if err := h.SubmitAfterJob(again, b.ID); !errors.Is(err, ErrDependencyCycle) {
+		t.Fatalf("cycle: err = %v, want ErrDependencyCycle", err)
+	}
+
+	if err := h.SubmitAfterJob(h.NewJob("c", nil), "missing"); !errors.Is(err, ErrJobNotFound) {
+		t.Fatalf("unknown dependency: err = %v, want ErrJobNotFound", err)
+	}
+}
@@ -0,0 +1,133 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime/debug"
+	"time"
+)
+
+// ErrorKind classifies why a job failed.
+type ErrorKind int
+
+const (
+	// KindInternal is a failure in the job's own code or environment.
+	KindInternal ErrorKind = iota
+	// KindTransient is a failure that may succeed if tried again, such as a
+	// timeout talking to another service.
+	KindTransient
+	// KindPermanent is a failure that will recur no matter how often the
+	// job is retried.
+	KindPermanent
+	// KindInvalidInput is a failure caused by what the user asked for.
+	KindInvalidInput
+)
+
+func (k ErrorKind) String() string {
+	switch k {
+	case KindTransient:
+		return "transient"
+	case KindPermanent:
+		return "permanent"
+	case KindInvalidInput:
+		return "invalid input"
+	default:
+		return "internal"
+	}
+}
+
+// JobError is a categorised job failure. Return one from a JobFunc (see
+// TransientError and PermanentError) and recover it from JobUpdate.Error or
+// Job.Error with errors.As. Errors that are not JobErrors are treated as
+// KindInternal and not retried.
+type JobError struct {
+	Kind      ErrorKind
+	Retryable bool
+	Err       error
+}
+
+func (e *JobError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *JobError) Unwrap() error {
+	return e.Err
+}
+
+// TransientError marks err as worth retrying.
+func TransientError(err error) error {
+	return &JobError{Kind: KindTransient, Retryable: true, Err: err}
+}
+
+// PermanentError marks err as final; the job is not retried.
+func PermanentError(err error) error {
+	return &JobError{Kind: KindPermanent, Err: err}
+}
+
+// PanicError is the failure of a job whose work panicked. The hub recovers
+// the panic, so it fails only that job; errors.As finds it in the job's
+// error, wrapped in a KindInternal JobError.
+type PanicError struct {
+	Value any
+	// Stack is the panicking goroutine's stack trace.
+	Stack []byte
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("panic: %v", e.Value)
+}
+
+// runWork calls the job's work, turning a panic into a PanicError.
+func (h *Hub) runWork(job *Job) (err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			perr := &PanicError{Value: p, Stack: debug.Stack()}
+			h.logger.Error("job panicked", "job_id", job.ID, "name", job.Name, "panic", p, "stack", string(perr.Stack))
+			err = &JobError{Kind: KindInternal, Err: perr}
+		}
+	}()
+	return job.work(job)
+}
+
+// IsRetryable reports whether err is a JobError marked Retryable.
+func IsRetryable(err error) bool {
+	var jerr *JobError
+	return errors.As(err, &jerr) && jerr.Retryable
+}
+
+// retryBackoff is the wait before retry attempt n (starting at 1).
+func retryBackoff(n int) time.Duration {
+	return min(500*time.Millisecond<<(n-1), 30*time.Second)
+}
+
+// SetMaxRetries lets the job run up to n more times after a failure
+// marked Retryable. It must be called before the job is submitted.
+func (j *Job) SetMaxRetries(n int) {
+	j.maxRetries = max(n, 0)
+}
+
+// runWithRetries runs the job's work, retrying Retryable failures with
+// exponential backoff until it succeeds, fails permanently, runs out of
+// retries or is cancelled.
+func (h *Hub) runWithRetries(job *Job) error {
+	for attempt := 0; ; attempt++ {
+		err := h.runWork(job)
+		if err == nil || !IsRetryable(err) || attempt >= job.maxRetries {
+			return err
+		}
+
+		wait := retryBackoff(attempt + 1)
+		h.logger.Warn("job failed, retrying", "job_id", job.ID, "attempt", attempt+1, "wait", wait, "error", err)
+		job.Log("attempt %d failed (%v); retrying in %s", attempt+1, err, wait)
+
+		select {
+		case <-time.After(wait):
+		case <-job.ctx.Done():
+			return errors.Join(err, context.Cause(job.ctx))
+		}
+		if rerr := job.resetResult(); rerr != nil {
+			return errors.Join(err, rerr)
+		}
+	}
+}
@@ -0,0 +1,32 @@
+package jobs
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestPanickingWorkFailsOnlyItsJob(t *testing.T) {
+	h := newTestHub(t, WithWorkers(1))
+
+	bad := h.NewJob("bad", func(*Job) error { panic("boom") })
+	if err := h.Submit(bad); err != nil {
+		t.Fatalf("Submit: %v", err)
+	}
+	u := waitFinal(t, bad)
+	var perr *PanicError
+	if !errors.As(u.Error, &perr) || perr.Value != "boom" {
+		t.Fatalf("final error = %v, want a PanicError of boom", u.Error)
+	}
+	if got := bad.Snapshot().Status; got != StatusFailed {
+		t.Errorf("status = %q, want %q", got, StatusFailed)
+	}
+
+	// The only worker survived the panic and runs the next job.
+	good := h.NewJob("good", func(*Job) error { return nil })
+	if err := h.Submit(good); err != nil {
+		t.Fatalf("Submit: %v", err)
+	}
+	if u := waitFinal(t, good); u.Error != nil {
+		t.Fatalf("next job failed: %v", u.Error)
+	}
+}
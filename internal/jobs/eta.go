@@ -0,0 +1,57 @@
+package jobs
+
+import "time"
+
+// etaSamples is how many recent progress samples ETA extrapolates from.
+const etaSamples = 10
+
+type progressSample struct {
+	at       time.Time
+	progress int
+}
+
+// recordSample appends a progress sample, keeping the last etaSamples.
+// Progress that goes backwards, as on a retry, starts a fresh window. The
+// caller must hold j.mu.
+func (j *Job) recordSample(p int, at time.Time) {
+	if n := len(j.samples); n > 0 && p < j.samples[n-1].progress {
+		j.samples = j.samples[:0]
+	}
+	if len(j.samples) == etaSamples {
+		j.samples = append(j.samples[:0], j.samples[1:]...)
+	}
+	j.samples = append(j.samples, progressSample{at: at, progress: p})
+}
+
+// ETA estimates the time until the job reaches 100% from the rate of its
+// recent progress. It returns zero when there's no estimate: too few
+// samples, no forward progress, or progress that has stalled for longer
+// than the sampled window.
+func (j *Job) ETA() time.Duration {
+	j.mu.RLock()
+	defer j.mu.RUnlock()
+	if j.Status != StatusRunning {
+		return 0
+	}
+	return estimateETA(j.samples, time.Now())
+}
+
+func estimateETA(samples []progressSample, now time.Time) time.Duration {
+	if len(samples) < 2 {
+		return 0
+	}
+	first, last := samples[0], samples[len(samples)-1]
+	span := last.at.Sub(first.at)
+	gained := last.progress - first.progress
+	if span <= 0 || gained <= 0 || last.progress >= 100 {
+		return 0
+	}
+	idle := now.Sub(last.at)
+	if idle > span {
+		return 0
+	}
+
+	perPoint := span / time.Duration(gained)
+	remaining := perPoint*time.Duration(100-last.progress) - idle
+	return max(remaining, 0)
+}
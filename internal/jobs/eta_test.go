@@ -0,0 +1,59 @@
+package jobs
+
+import (
+	"testing"
+	"time"
+)
+
+func TestEstimateETA(t *testing.T) {
+	base := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	// samples builds one sample per second with the given progress values.
+	samples := func(progress ...int) []progressSample {
+		var s []progressSample
+		for i, p := range progress {
+			s = append(s, progressSample{at: base.Add(time.Duration(i) * time.Second), progress: p})
+		}
+		return s
+	}
+
+	tests := []struct {
+		name    string
+		samples []progressSample
+		idle    time.Duration // since the last sample
+		want    time.Duration
+	}{
+		{"steady", samples(10, 20, 30, 40), 0, 6 * time.Second},
+		{"steady, partway to the next sample", samples(10, 20, 30, 40), 500 * time.Millisecond, 5500 * time.Millisecond},
+		{"stalled within the window", samples(10, 20, 30, 30, 30), 0, 14 * time.Second},
+		{"stalled past the window", samples(10, 20, 30), 3 * time.Second, 0},
+		{"zero progress", samples(0, 0, 0, 0), 0, 0},
+		{"single sample", samples(50), 0, 0},
+		{"done", samples(80, 90, 100), 0, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			now := tt.samples[len(tt.samples)-1].at.Add(tt.idle)
+			if got := estimateETA(tt.samples, now); got != tt.want {
+				t.Errorf("ETA = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRecordSampleRestartsOnRegress(t *testing.T) {
+	var j Job
+	now := time.Now()
+	for i, p := range []int{10, 20, 30, 5, 15} {
+		j.recordSample(p, now.Add(time.Duration(i)*time.Second))
+	}
+	if len(j.samples) != 2 || j.samples[0].progress != 5 {
+		t.Fatalf("samples = %+v, want a fresh window from 5", j.samples)
+	}
+
+	for i := range 2 * etaSamples {
+		j.recordSample(20+i, now.Add(time.Duration(10+i)*time.Second))
+	}
+	if len(j.samples) != etaSamples {
+		t.Fatalf("%d samples kept, want %d", len(j.samples), etaSamples)
+	}
+}
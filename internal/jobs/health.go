@@ -0,0 +1,73 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+)
+
+var discardLogger = slog.New(slog.DiscardHandler)
+
+// Ping runs a no-op job through the run loop and waits for it to finish,
+// so a full submit queue or a hung loop surfaces as an error instead of
+// jobs silently never starting. The ping job skips the queue and runs in
+// its own goroutine, so workers all busy with long jobs don't make the hub
+// look unhealthy. Ping jobs are not stored, listed or logged.
+func (h *Hub) Ping(ctx context.Context) error {
+	if h.stopping.Load() {
+		return ErrHubStopped
+	}
+	if len(h.submit) == cap(h.submit) {
+		return ErrQueueFull
+	}
+
+	job := h.NewJob("ping", func(*Job) error { return nil })
+	job.internal = true
+	job.logger = discardLogger
+
+	select {
+	case h.pings <- job:
+	case <-h.done:
+		return ErrHubStopped
+	case <-ctx.Done():
+		return fmt.Errorf("jobs: ping was not picked up: %w", ctx.Err())
+	}
+
+	for {
+		select {
+		case update, ok := <-job.updates:
+			if !ok {
+				return nil
+			}
+			if update.Done {
+				return update.Error
+			}
+		case <-ctx.Done():
+			// Let the job finish immediately if the loop ever picks it up.
+			job.Cancel()
+			return fmt.Errorf("jobs: ping did not complete: %w", ctx.Err())
+		}
+	}
+}
+
+// servePings runs ping jobs until Stop is called. With a worker pool it
+// stands in for the spawn-per-job loop, which serves them itself.
+func (h *Hub) servePings() {
+	for {
+		select {
+		case job := <-h.pings:
+			go h.execute(job)
+		case <-h.done:
+			return
+		}
+	}
+}
+
+// loggerFor returns the logger for job's lifecycle messages; internal jobs
+// are silent.
+func (h *Hub) loggerFor(job *Job) *slog.Logger {
+	if job.internal {
+		return discardLogger
+	}
+	return h.logger
+}
@@ -0,0 +1,56 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func pingCtx(t *testing.T) context.Context {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	t.Cleanup(cancel)
+	return ctx
+}
+
+func TestPingWithBusyWorkers(t *testing.T) {
+	const workers = 2
+	h := newTestHub(t, WithWorkers(workers))
+
+	started := make(chan struct{}, workers)
+	for range workers {
+		job := h.NewJob("long", func(j *Job) error {
+			started <- struct{}{}
+			<-j.Context().Done()
+			return j.Context().Err()
+		})
+		if err := h.Submit(job); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for range workers {
+		<-started
+	}
+
+	if err := h.Ping(pingCtx(t)); err != nil {
+		t.Fatalf("Ping with every worker busy = %v, want nil", err)
+	}
+	if got := len(h.ListSorted()); got != workers {
+		t.Fatalf("%d jobs listed, want the ping job hidden", got)
+	}
+}
+
+func TestPingQueueFull(t *testing.T) {
+	h, _ := fullHub(t)
+	if err := h.Ping(pingCtx(t)); !errors.Is(err, ErrQueueFull) {
+		t.Fatalf("Ping = %v, want ErrQueueFull", err)
+	}
+}
+
+func TestPingStopped(t *testing.T) {
+	h := newTestHub(t)
+	h.Stop()
+	if err := h.Ping(pingCtx(t)); !errors.Is(err, ErrHubStopped) {
+		t.Fatalf("Ping = %v, want ErrHubStopped", err)
+	}
+}
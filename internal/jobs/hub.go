@@ -2,47 +2,146 @@ package jobs
 
 import (
 	"context"
+	"errors"
 	"log/slog"
+	"slices"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/ankit-lilly/go-datastar-daisyui-template/internal/util"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var tracer = otel.Tracer("github.com/ankit-lilly/go-datastar-daisyui-template/internal/jobs")
+
+const (
+	StatusPending   = "pending"
+	StatusRunning   = "running"
+	StatusPaused    = "paused"
+	StatusCompleted = "completed"
+	StatusFailed    = "failed"
+	// StatusInterrupted marks jobs that were pending or running when the
+	// process stopped; they are restored in this state from a JobStore.
+	StatusInterrupted = "interrupted"
+	// StatusSkipped marks jobs that never ran because a job they depended
+	// on did not complete successfully.
+	StatusSkipped = "skipped"
+	// StatusRejected marks jobs that never ran because the submit queue was
+	// full, their owner was at its cap or the hub was stopping.
+	StatusRejected = "rejected"
+)
+
+var (
+	ErrQueueFull  = errors.New("jobs: submit queue full")
+	ErrHubStopped = errors.New("jobs: hub is stopping")
+	// ErrDuplicateJob is returned for a job that was already submitted, or
+	// whose ID belongs to another job that has not finished.
+	ErrDuplicateJob = errors.New("jobs: job already submitted")
 )
 
 type JobFunc func(j *Job) error
 
 type JobUpdate struct {
 	Progress int
-	Done     bool
-	Error    error
+	// LogLine is set on updates emitted by Job.Log.
+	LogLine string
+	// Status is set on updates emitted by Pause and Resume.
+	Status string
+	// ETA is the estimated time remaining, or zero when unknown; see
+	// Job.ETA.
+	ETA   time.Duration
+	Done  bool
+	Error error
 }
 
+// Job is a unit of background work. ID and Name are fixed at creation;
+// Status, Progress, the timestamps and Error change while the job runs and
+// are guarded by its lock, so code outside this package reads them through
+// Snapshot.
 type Job struct {
-	ID        string
-	Name      string
-	Status    string
-	Progress  int
-	CreatedAt time.Time
-	Error     error
+	ID         string
+	Name       string
+	Status     string
+	Progress   int
+	CreatedAt  time.Time
+	StartedAt  time.Time
+	FinishedAt time.Time
+	Error      error
+
+	// Owner identifies the client that submitted the job, for the hub's
+	// per-owner cap (see WithMaxJobsPerOwner). It is not persisted.
+	Owner string
+	// ownerClaimed is set while the job counts against Owner's cap. It is
+	// guarded by the hub's mutex.
+	ownerClaimed bool
+
+	logs []string
+
+	// resume is non-nil while paused and closed by Resume.
+	resume chan struct{}
+
+	maxRetries int
+	// timeout, when positive, bounds the job's run; see watchdog.go.
+	timeout time.Duration
+
+	// closed is set, under mu, once updates is closed; later updates from
+	// abandoned work are dropped.
+	closed bool
+
+	// logger receives debug progress lines every progressLogStep percent;
+	// loggedBucket is the last Progress/progressLogStep logged.
+	logger          *slog.Logger
+	progressLogStep int
+	loggedBucket    int
+
+	// progressInterval throttles progress updates; progressSent is when the
+	// last one was emitted and flushPending whether a trailing one is
+	// scheduled. See throttle.go.
+	progressInterval time.Duration
+	progressSent     time.Time
+	flushPending     bool
+
+	// internal jobs, such as health-check pings, are never stored, listed
+	// or logged.
+	internal bool
 
 	ctx     context.Context
 	cancel  context.CancelFunc
 	work    JobFunc
 	updates chan JobUpdate
 	mu      sync.RWMutex
+
+	// link points the job's span at the span that submitted it.
+	link trace.Link
+
+	// result is the job's spooled output, if it wrote any; see result.go.
+	result *result
+
+	// samples holds recent progress for ETA; see eta.go.
+	samples []progressSample
 }
 
-func newJob(name string, work JobFunc) *Job {
+// newJob creates a pending job. A non-empty idPrefix is joined to the
+// random ID with a hyphen.
+func newJob(name string, work JobFunc, updateBuffer int, idPrefix string) *Job {
+	id := util.GenerateID()
+	if idPrefix != "" {
+		id = idPrefix + "-" + id
+	}
 	ctx, cancel := context.WithCancel(context.Background())
 	return &Job{
-		ID:        util.GenerateID(),
+		ID:        id,
 		Name:      name,
-		Status:    "pending",
+		Status:    StatusPending,
 		CreatedAt: time.Now(),
 		ctx:       ctx,
 		cancel:    cancel,
 		work:      work,
-		updates:   make(chan JobUpdate, 100),
+		updates:   make(chan JobUpdate, updateBuffer),
 	}
 }
 
@@ -50,17 +149,64 @@ func (j *Job) Context() context.Context {
 	return j.ctx
 }
 
+// SetProgress records p and emits an update without blocking. If the
+// update buffer is full because the consumer is slow or gone, the update is
+// dropped; the consumer still sees the latest value on the next update that
+// fits, and the terminal update is always delivered. Updates may also be
+// coalesced by the job's progress interval (see SetProgressInterval).
+// Crossing a multiple of the hub's progress log step is logged at debug
+// level.
 func (j *Job) SetProgress(p int) {
 	j.mu.Lock()
+	if j.closed {
+		j.mu.Unlock()
+		return
+	}
 	j.Progress = p
+	now := time.Now()
+	j.recordSample(p, now)
+	eta := estimateETA(j.samples, now)
+	logProgress := false
+	if j.progressLogStep > 0 {
+		if bucket := p / j.progressLogStep; bucket != j.loggedBucket {
+			j.loggedBucket = bucket
+			logProgress = true
+		}
+	}
+	held := j.throttled(now)
 	j.mu.Unlock()
 
+	if logProgress {
+		j.logger.Debug("job progress", "job_id", j.ID, "name", j.Name, "progress", p)
+	}
+
+	if !held {
+		j.emit(JobUpdate{Progress: p, ETA: eta})
+	}
+}
+
+// emit sends u without blocking, dropping it if the buffer is full or the
+// job has finished.
+func (j *Job) emit(u JobUpdate) {
+	j.mu.RLock()
+	defer j.mu.RUnlock()
+	if j.closed {
+		return
+	}
 	select {
-	case j.updates <- JobUpdate{Progress: p}:
+	case j.updates <- u:
 	default:
 	}
 }
 
+// closeUpdates closes the update channel after the terminal update.
+func (j *Job) closeUpdates() {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.closed = true
+	close(j.updates)
+}
+
 func (j *Job) Updates() <-chan JobUpdate {
 	return j.updates
 }
@@ -69,92 +215,505 @@ func (j *Job) Cancel() {
 	j.cancel()
 }
 
+const DefaultUpdateBuffer = 100
+
+// DefaultProgressLogStep is the progress interval, in percent, between debug
+// log lines.
+const DefaultProgressLogStep = 10
+
 type Hub struct {
-	jobs   map[string]*Job
-	submit chan *Job
-	done   chan struct{}
-	logger *slog.Logger
-	mu     sync.RWMutex
+	jobs     map[string]*Job
+	store    JobStore
+	submit   chan *Job
+	pings    chan *Job // Ping jobs, which skip the submit queue
+	done     chan struct{}
+	stopOnce sync.Once
+	// stopping is set once Stop or StopGraceful begins; new jobs are then
+	// rejected.
+	stopping     atomic.Bool
+	logger       *slog.Logger
+	updateBuffer int
+	progressStep int
+	// progressInterval is the default for new jobs; see throttle.go.
+	progressInterval time.Duration
+	workers          int
+	idPrefix         string
+	maxPerOwner      int
+	queuePolicy      QueuePolicy
+	queueTimeout     time.Duration
+	// retention is how long finished jobs are kept; see retention.go.
+	retention time.Duration
+
+	// owners counts the active jobs of each owner; see owner.go.
+	owners map[string]int
+
+	// Dependency registry; see deps.go.
+	dependsOn  map[string][]string
+	waitingOn  map[string]map[string]bool
+	dependents map[string][]string
+
+	mu sync.RWMutex
+}
+
+type Option func(*Hub)
+
+// WithStore persists job metadata in store instead of the default
+// MemoryStore.
+func WithStore(store JobStore) Option {
+	return func(h *Hub) {
+		h.store = store
+	}
+}
+
+// WithUpdateBuffer sets the capacity of each job's update channel
+// (default DefaultUpdateBuffer). Larger buffers suit jobs reporting many
+// fine-grained steps to slow consumers; progress updates that don't fit are
+// dropped. Values below 1 are raised to 1 so the terminal update can always
+// be delivered.
+func WithUpdateBuffer(n int) Option {
+	return func(h *Hub) {
+		h.updateBuffer = max(n, 1)
+	}
+}
+
+// WithProgressLogStep logs job progress at debug level each time it crosses
+// a multiple of step percent (default DefaultProgressLogStep). Zero turns
+// progress logging off.
+func WithProgressLogStep(step int) Option {
+	return func(h *Hub) {
+		h.progressStep = max(step, 0)
+	}
+}
+
+// WithWorkers makes Run start n long-lived workers that take jobs from the
+// submit queue, instead of a goroutine per job. At most n jobs then run at
+// once and the rest wait in the queue. Zero, the default, keeps one
+// goroutine per job.
+func WithWorkers(n int) Option {
+	return func(h *Hub) {
+		h.workers = max(n, 0)
+	}
+}
+
+// WithIDPrefix starts every job ID with prefix and a hyphen, such as
+// "web1-3f9c...", so IDs in logs shared by several instances can be traced
+// to the one that ran the job. The prefix ends up in URLs and must be
+// URL-safe.
+func WithIDPrefix(prefix string) Option {
+	return func(h *Hub) {
+		h.idPrefix = prefix
+	}
 }
 
-func NewHub(logger *slog.Logger) *Hub {
-	return &Hub{
-		jobs:   make(map[string]*Job),
-		submit: make(chan *Job, 100),
-		done:   make(chan struct{}),
-		logger: logger,
+func NewHub(logger *slog.Logger, opts ...Option) *Hub {
+	h := &Hub{
+		jobs:         make(map[string]*Job),
+		store:        NewMemoryStore(),
+		submit:       make(chan *Job, 100),
+		pings:        make(chan *Job),
+		done:         make(chan struct{}),
+		logger:       logger,
+		updateBuffer: DefaultUpdateBuffer,
+		progressStep: DefaultProgressLogStep,
+		owners:       make(map[string]int),
+		dependsOn:    make(map[string][]string),
+		waitingOn:    make(map[string]map[string]bool),
+		dependents:   make(map[string][]string),
+	}
+	for _, opt := range opts {
+		opt(h)
+	}
+	h.markInterrupted()
+	return h
+}
+
+// markInterrupted flags jobs persisted as pending or running by a previous
+// process, since their work functions are gone.
+func (h *Hub) markInterrupted() {
+	jobs, err := h.store.List()
+	if err != nil {
+		h.logger.Error("failed to list stored jobs", "error", err)
+		return
+	}
+	for _, job := range jobs {
+		if job.Status != StatusPending && job.Status != StatusRunning && job.Status != StatusPaused {
+			continue
+		}
+		if err := h.store.UpdateStatus(job.ID, StatusInterrupted, job.Progress, job.Error); err != nil {
+			h.logger.Error("failed to mark job interrupted", "job_id", job.ID, "error", err)
+			continue
+		}
+		h.logger.Warn("job interrupted by restart", "job_id", job.ID, "name", job.Name)
 	}
 }
 
+// Run executes submitted jobs until Stop is called. With WithRetention it
+// also reaps finished jobs.
 func (h *Hub) Run() {
+	if h.retention > 0 {
+		go h.reapLoop()
+	}
+	if h.workers > 0 {
+		var wg sync.WaitGroup
+		for range h.workers {
+			wg.Go(h.work)
+		}
+		wg.Go(h.servePings)
+		wg.Wait()
+		return
+	}
+
 	for {
 		select {
 		case job := <-h.submit:
 			go h.execute(job)
+		case job := <-h.pings:
+			go h.execute(job)
+		case <-h.done:
+			return
+		}
+	}
+}
+
+// work runs queued jobs one after another until Stop is called.
+func (h *Hub) work() {
+	for {
+		select {
+		case job := <-h.submit:
+			h.execute(job)
 		case <-h.done:
 			return
 		}
 	}
 }
 
+// Stop stops the run loop, rejects queued jobs with ErrHubStopped, cancels
+// every job and removes their result files.
 func (h *Hub) Stop() {
-	close(h.done)
+	h.stopAccepting()
+	h.rejectQueued()
 
 	h.mu.RLock()
 	for _, job := range h.jobs {
 		job.Cancel()
+		job.removeResult()
+	}
+	h.mu.RUnlock()
+}
+
+// stopPollInterval is how often StopGraceful checks for running jobs.
+const stopPollInterval = 50 * time.Millisecond
+
+// StopGraceful stops accepting jobs and waits for running ones to finish
+// until ctx is done, then stops as Stop does, cancelling any stragglers.
+// Queued jobs that have not started are rejected with ErrHubStopped, and
+// paused jobs are cancelled at once since they would not finish on their
+// own. It returns ctx's error if jobs were still running when it expired.
+func (h *Hub) StopGraceful(ctx context.Context) error {
+	h.stopAccepting()
+	h.rejectQueued()
+	for _, job := range h.withStatus(StatusPaused) {
+		job.Cancel()
+	}
+
+	ticker := time.NewTicker(stopPollInterval)
+	defer ticker.Stop()
+	for n := h.Running(); n > 0; n = h.Running() {
+		select {
+		case <-ctx.Done():
+			h.logger.Warn("shutdown grace expired, cancelling running jobs", "count", n)
+			h.Stop()
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+	h.Stop()
+	return nil
+}
+
+// stopAccepting makes enqueue reject new jobs and stops the run loop. It
+// is safe to call more than once.
+func (h *Hub) stopAccepting() {
+	h.stopOnce.Do(func() {
+		h.stopping.Store(true)
+		close(h.done)
+	})
+}
+
+// rejectQueued finishes jobs still waiting in the submit queue.
+func (h *Hub) rejectQueued() {
+	for {
+		select {
+		case job := <-h.submit:
+			h.finishUnrun(job, StatusRejected, ErrHubStopped)
+		default:
+			return
+		}
+	}
+}
+
+// withStatus returns the live jobs whose status is one of statuses.
+func (h *Hub) withStatus(statuses ...string) []*Job {
+	var found []*Job
+	h.mu.RLock()
+	for _, job := range h.jobs {
+		job.mu.RLock()
+		status := job.Status
+		job.mu.RUnlock()
+		if slices.Contains(statuses, status) {
+			found = append(found, job)
+		}
 	}
 	h.mu.RUnlock()
+	return found
+}
+
+// CancelAll cancels every pending, running or paused job and returns how
+// many it cancelled. Running jobs fail once their work notices the
+// cancellation; pending ones fail without running. The jobs are collected
+// under the lock and cancelled after it is released, so cancellation never
+// waits on execute or release.
+func (h *Hub) CancelAll() int {
+	active := h.withStatus(StatusPending, StatusRunning, StatusPaused)
+	for _, job := range active {
+		job.Cancel()
+	}
+	h.logger.Warn("cancelled all active jobs", "count", len(active))
+	return len(active)
+}
+
+// Running returns how many jobs are running or paused.
+func (h *Hub) Running() int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	n := 0
+	for _, job := range h.jobs {
+		job.mu.RLock()
+		if job.Status == StatusRunning || job.Status == StatusPaused {
+			n++
+		}
+		job.mu.RUnlock()
+	}
+	return n
 }
 
 func (h *Hub) NewJob(name string, work JobFunc) *Job {
-	return newJob(name, work)
+	job := newJob(name, work, h.updateBuffer, h.idPrefix)
+	job.logger = h.logger
+	job.progressLogStep = h.progressStep
+	job.progressInterval = h.progressInterval
+	return job
+}
+
+// Submit queues job to run. If the queue is full it returns ErrQueueFull,
+// or ErrOwnerLimit if its Owner has too many active jobs, and the job is
+// finished as StatusRejected without running, so callers must not report
+// it as started. Submitting a job twice returns ErrDuplicateJob and leaves
+// the job as it was; use Clone to run the same work again.
+func (h *Hub) Submit(job *Job) error {
+	return h.SubmitContext(context.Background(), job)
 }
 
-func (h *Hub) Submit(job *Job) {
+// SubmitContext is like Submit but links the job's span to the span in ctx,
+// typically the request that started the job. The job outlives the request,
+// so its span is a new trace rather than a child.
+func (h *Hub) SubmitContext(ctx context.Context, job *Job) error {
 	h.mu.Lock()
+	if h.submittedLocked(job) {
+		h.mu.Unlock()
+		return h.rejectDuplicate(job)
+	}
+	job.link = trace.LinkFromContext(ctx)
 	h.jobs[job.ID] = job
+	fits := h.claimOwnerLocked(job)
 	h.mu.Unlock()
 
-	select {
-	case h.submit <- job:
-	default:
-		h.logger.Warn("job queue full", "job_id", job.ID)
+	h.persist(job)
+	auditJob(job, "job.submitted")
+	if !fits {
+		return h.rejectOwner(job)
+	}
+	return h.enqueue(ctx, job)
+}
+
+// submittedLocked reports whether job was already submitted, or its ID is
+// taken by a job that is still pending, running or paused. h.mu must be
+// held.
+func (h *Hub) submittedLocked(job *Job) bool {
+	prev, ok := h.jobs[job.ID]
+	if !ok {
+		return false
+	}
+	if prev == job {
+		return true
+	}
+	switch prev.Snapshot().Status {
+	case StatusPending, StatusRunning, StatusPaused:
+		return true
+	}
+	return false
+}
+
+// rejectDuplicate turns away a second submission of job. Unlike the other
+// rejections the job is not finished: the first submission still owns it.
+func (h *Hub) rejectDuplicate(job *Job) error {
+	h.logger.Warn("duplicate job submission ignored", "job_id", job.ID, "name", job.Name)
+	return ErrDuplicateJob
+}
+
+// finishUnrun ends a job that never started with status and err, delivers
+// the terminal update and skips its dependents.
+func (h *Hub) finishUnrun(job *Job, status string, err error) {
+	job.mu.Lock()
+	job.Status = status
+	job.Error = err
+	job.FinishedAt = time.Now()
+	progress := job.Progress
+	job.mu.Unlock()
+	h.persist(job)
+	auditJob(job, "job."+status)
+	job.cancel()
+
+	sendFinal(job.updates, JobUpdate{Progress: progress, Done: true, Error: err})
+	job.closeUpdates()
+
+	h.releaseOwner(job)
+	h.release(job.ID, false)
+}
+
+// Clone submits a new job with a fresh ID and context but the same name,
+// work, retry limit and Owner as job id, and returns it. The clone shares
+// nothing else with the original, so it is not tied to whatever request
+// started that one. It returns false for unknown jobs and for jobs loaded
+// from the store, whose work did not survive the restart. If the clone is
+// rejected, as with Submit, its status and Error say why.
+func (h *Hub) Clone(id string) (*Job, bool) {
+	h.mu.RLock()
+	orig, ok := h.jobs[id]
+	h.mu.RUnlock()
+	if !ok || orig.work == nil || orig.internal {
+		return nil, false
+	}
+
+	job := h.NewJob(orig.Name, orig.work)
+	job.Owner = orig.Owner
+	job.maxRetries = orig.maxRetries
+	job.timeout = orig.timeout
+	job.progressInterval = orig.progressInterval
+	if err := h.Submit(job); err != nil {
+		h.logger.Warn("job clone rejected", "job_id", job.ID, "clone_of", id, "error", err)
+	} else {
+		h.logger.Info("job cloned", "job_id", job.ID, "clone_of", id)
 	}
+	return job, true
 }
 
+// Get returns a live job, falling back to the store for jobs from earlier
+// runs.
 func (h *Hub) Get(id string) (*Job, bool) {
 	h.mu.RLock()
-	defer h.mu.RUnlock()
 	job, ok := h.jobs[id]
-	return job, ok
+	h.mu.RUnlock()
+	if ok {
+		return job, true
+	}
+
+	job, err := h.store.Load(id)
+	if err != nil {
+		return nil, false
+	}
+	return job, true
 }
 
 func (h *Hub) execute(job *Job) {
+	var opts []trace.SpanStartOption
+	if job.link.SpanContext.IsValid() {
+		opts = append(opts, trace.WithLinks(job.link))
+	}
+	opts = append(opts, trace.WithAttributes(
+		attribute.String("job.id", job.ID),
+		attribute.String("job.name", job.Name),
+	))
+	_, span := tracer.Start(context.Background(), "job "+job.Name, opts...)
+	defer span.End()
+
+	logger := h.loggerFor(job)
+
+	// Jobs cancelled while queued, e.g. by CancelAll, fail without running.
+	if err := job.ctx.Err(); err != nil {
+		logger.Info("job cancelled before starting", "job_id", job.ID)
+		h.finishUnrun(job, StatusFailed, err)
+		return
+	}
+
 	job.mu.Lock()
-	job.Status = "running"
+	job.Status = StatusRunning
+	job.StartedAt = time.Now()
 	job.mu.Unlock()
+	h.persist(job)
 
-	h.logger.Info("job started", "job_id", job.ID, "name", job.Name)
+	logger.Info("job started", "job_id", job.ID, "name", job.Name)
 
-	err := job.work(job)
+	err := h.runWatched(job, logger)
 
 	job.mu.Lock()
+	job.FinishedAt = time.Now()
+	job.resume = nil
 	if err != nil {
-		job.Status = "failed"
+		job.Status = StatusFailed
 		job.Error = err
-		h.logger.Error("job failed", "job_id", job.ID, "error", err)
+		logger.Error("job failed", "job_id", job.ID, "error", err)
 	} else {
-		job.Status = "completed"
+		job.Status = StatusCompleted
 		job.Progress = 100
-		h.logger.Info("job completed", "job_id", job.ID)
+		logger.Info("job completed", "job_id", job.ID)
 	}
+	status, progress := job.Status, job.Progress
 	job.mu.Unlock()
+	job.finishResult()
+	h.persist(job)
+	auditJob(job, "job."+status)
 
-	job.updates <- JobUpdate{
-		Progress: job.Progress,
+	span.SetAttributes(attribute.String("job.status", status))
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+
+	sendFinal(job.updates, JobUpdate{
+		Progress: progress,
 		Done:     true,
 		Error:    err,
+	})
+	job.closeUpdates()
+
+	h.releaseOwner(job)
+	h.release(job.ID, err == nil)
+}
+
+func (h *Hub) persist(job *Job) {
+	if job.internal {
+		return
+	}
+	if err := h.store.Save(job); err != nil {
+		h.logger.Error("failed to persist job", "job_id", job.ID, "error", err)
+	}
+}
+
+// sendFinal delivers the terminal update without blocking. If nobody is
+// draining the channel (e.g. the client disconnected) and it is full, the
+// oldest progress updates are discarded to make room, so execute never
+// leaks waiting on an abandoned stream.
+func sendFinal(updates chan JobUpdate, final JobUpdate) {
+	for {
+		select {
+		case updates <- final:
+			return
+		default:
+			select {
+			case <-updates:
+			default:
+			}
+		}
 	}
-	close(job.updates)
 }
@@ -0,0 +1,238 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newTestHub returns a running hub built with opts that is stopped when the
+// test ends.
+func newTestHub(t testing.TB, opts ...Option) *Hub {
+	t.Helper()
+	h := NewHub(slog.New(slog.DiscardHandler), opts...)
+	go h.Run()
+	t.Cleanup(h.Stop)
+	return h
+}
+
+// waitFinal drains job's updates and returns the terminal one.
+func waitFinal(t testing.TB, job *Job) JobUpdate {
+	t.Helper()
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case u, ok := <-job.Updates():
+			if !ok {
+				t.Fatalf("job %s: updates closed without a terminal update", job.ID)
+			}
+			if u.Done {
+				return u
+			}
+		case <-timeout:
+			t.Fatalf("job %s did not finish", job.ID)
+		}
+	}
+}
+
+func TestTinyUpdateBufferDoesNotBlock(t *testing.T) {
+	h := newTestHub(t, WithUpdateBuffer(0))
+	job := h.NewJob("chatty", func(j *Job) error {
+		for i := range 1000 {
+			j.SetProgress(i % 100)
+			j.Log("step %d", i)
+		}
+		return nil
+	})
+	if err := h.Submit(job); err != nil {
+		t.Fatalf("Submit: %v", err)
+	}
+
+	// Nobody reads until the work is done; it must not block on the full
+	// channel.
+	deadline := time.Now().Add(5 * time.Second)
+	for job.Snapshot().Status != StatusCompleted {
+		if time.Now().After(deadline) {
+			t.Fatalf("job stuck with status %q", job.Snapshot().Status)
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	if cap(job.Updates()) != 1 {
+		t.Fatalf("buffer = %d, want 1", cap(job.Updates()))
+	}
+	if u := waitFinal(t, job); u.Error != nil || u.Progress != 100 {
+		t.Fatalf("final update = %+v, want success at 100", u)
+	}
+	if _, ok := <-job.Updates(); ok {
+		t.Fatal("updates not closed after the terminal update")
+	}
+}
+
+func TestSubmitRejectsDuplicates(t *testing.T) {
+	h := newTestHub(t)
+	var runs atomic.Int32
+	release := make(chan struct{})
+	work := func(*Job) error {
+		runs.Add(1)
+		<-release
+		return nil
+	}
+
+	job := h.NewJob("once", work)
+	if err := h.Submit(job); err != nil {
+		t.Fatalf("first Submit: %v", err)
+	}
+	if err := h.Submit(job); !errors.Is(err, ErrDuplicateJob) {
+		t.Fatalf("second Submit = %v, want ErrDuplicateJob", err)
+	}
+	if err := h.SubmitAfterJob(job); !errors.Is(err, ErrDuplicateJob) {
+		t.Fatalf("SubmitAfterJob = %v, want ErrDuplicateJob", err)
+	}
+
+	// Another job reusing a live job's ID is a duplicate too.
+	impostor := h.NewJob("impostor", work)
+	impostor.ID = job.ID
+	if err := h.Submit(impostor); !errors.Is(err, ErrDuplicateJob) {
+		t.Fatalf("Submit with a live ID = %v, want ErrDuplicateJob", err)
+	}
+
+	// A distinct job is accepted.
+	other := h.NewJob("other", work)
+	if err := h.Submit(other); err != nil {
+		t.Fatalf("distinct Submit: %v", err)
+	}
+
+	close(release)
+	for _, j := range []*Job{job, other} {
+		if u := waitFinal(t, j); u.Error != nil {
+			t.Fatalf("%s failed: %v", j.Name, u.Error)
+		}
+	}
+	if n := runs.Load(); n != 2 {
+		t.Fatalf("work ran %d times, want 2", n)
+	}
+
+	// Even finished, the same job can't be submitted again.
+	if err := h.Submit(job); !errors.Is(err, ErrDuplicateJob) {
+		t.Fatalf("Submit after finishing = %v, want ErrDuplicateJob", err)
+	}
+}
+
+func TestStopGracefulCancelsStragglers(t *testing.T) {
+	h := NewHub(slog.New(slog.DiscardHandler))
+	go h.Run()
+
+	short := h.NewJob("short", func(*Job) error {
+		time.Sleep(20 * time.Millisecond)
+		return nil
+	})
+	long := h.NewJob("long", func(j *Job) error {
+		<-j.Context().Done()
+		return j.Context().Err()
+	})
+	for _, job := range []*Job{short, long} {
+		if err := h.Submit(job); err != nil {
+			t.Fatalf("Submit %s: %v", job.Name, err)
+		}
+	}
+	for long.Snapshot().Status != StatusRunning {
+		time.Sleep(time.Millisecond)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	if err := h.StopGraceful(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("StopGraceful = %v, want DeadlineExceeded", err)
+	}
+
+	if u := waitFinal(t, short); u.Error != nil {
+		t.Errorf("short job failed: %v", u.Error)
+	}
+	if u := waitFinal(t, long); !errors.Is(u.Error, context.Canceled) {
+		t.Errorf("long job error = %v, want context.Canceled", u.Error)
+	}
+	if got := long.Snapshot().Status; got != StatusFailed {
+		t.Errorf("long job status = %q, want %q", got, StatusFailed)
+	}
+}
+
+func TestIDPrefix(t *testing.T) {
+	h := NewHub(slog.New(slog.DiscardHandler), WithIDPrefix("web1"))
+	seen := make(map[string]bool)
+	for range 1000 {
+		id := h.NewJob("x", func(*Job) error { return nil }).ID
+		if !strings.HasPrefix(id, "web1-") || len(id) == len("web1-") {
+			t.Fatalf("ID %q, want web1- followed by a random part", id)
+		}
+		if seen[id] {
+			t.Fatalf("duplicate ID %q", id)
+		}
+		seen[id] = true
+	}
+
+	if id := NewHub(slog.New(slog.DiscardHandler)).NewJob("x", nil).ID; strings.Contains(id, "-") {
+		t.Errorf("ID without a prefix = %q, want no hyphen", id)
+	}
+}
+
+func TestCloneIsIndependent(t *testing.T) {
+	store := NewMemoryStore()
+	old := jobRecord{ID: "from-last-run", Name: "old", Status: StatusCompleted}.job()
+	if err := store.Save(old); err != nil {
+		t.Fatal(err)
+	}
+	h := newTestHub(t, WithStore(store))
+
+	var runs atomic.Int32
+	orig := h.NewJob("report", func(j *Job) error {
+		runs.Add(1)
+		j.Log("run %d", runs.Load())
+		return nil
+	})
+	orig.Owner = "alice"
+	orig.SetMaxRetries(2)
+	orig.SetTimeout(time.Minute)
+	if err := h.Submit(orig); err != nil {
+		t.Fatalf("Submit: %v", err)
+	}
+	waitFinal(t, orig)
+	origLogs := orig.Logs()
+
+	clone, ok := h.Clone(orig.ID)
+	if !ok {
+		t.Fatal("Clone of a finished live job failed")
+	}
+	if u := waitFinal(t, clone); u.Error != nil {
+		t.Fatalf("clone failed: %v", u.Error)
+	}
+	if clone.ID == orig.ID || clone.Name != orig.Name || clone.Owner != orig.Owner {
+		t.Errorf("clone %s %q owned by %q, want a new ID for %q owned by %q", clone.ID, clone.Name, clone.Owner, orig.Name, orig.Owner)
+	}
+	if clone.maxRetries != 2 || clone.timeout != time.Minute {
+		t.Errorf("clone retries %d, timeout %v; want the original's settings", clone.maxRetries, clone.timeout)
+	}
+
+	// The clone's log and state are its own.
+	clone.Log("only on the clone")
+	if got := orig.Logs(); !slices.Equal(got, origLogs) {
+		t.Errorf("original log changed to %v, want %v", got, origLogs)
+	}
+	if logs := clone.Logs(); len(logs) != 2 || !strings.HasSuffix(logs[0], "run 2") {
+		t.Errorf("clone log = %v, want its own run and line", logs)
+	}
+	if got := orig.Snapshot().Status; got != StatusCompleted {
+		t.Errorf("original status %q, want %q", got, StatusCompleted)
+	}
+
+	for _, id := range []string{"no-such-job", old.ID} {
+		if _, ok := h.Clone(id); ok {
+			t.Errorf("Clone(%q) succeeded, want false", id)
+		}
+	}
+}
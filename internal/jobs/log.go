@@ -0,0 +1,35 @@
+package jobs
+
+import (
+	"fmt"
+	"slices"
+	"time"
+)
+
+// MaxLogLines bounds each job's log; older lines are dropped first.
+const MaxLogLines = 200
+
+// Log appends a timestamped line to the job's log and emits it as an
+// update. Like SetProgress it never blocks: if the update buffer is full
+// the line is still kept in Logs but not streamed.
+func (j *Job) Log(format string, args ...any) {
+	line := time.Now().Format("15:04:05.000") + " " + fmt.Sprintf(format, args...)
+
+	j.mu.Lock()
+	if len(j.logs) >= MaxLogLines {
+		j.logs = slices.Delete(j.logs, 0, len(j.logs)-MaxLogLines+1)
+	}
+	j.logs = append(j.logs, line)
+	progress := j.Progress
+	eta := estimateETA(j.samples, time.Now())
+	j.mu.Unlock()
+
+	j.emit(JobUpdate{Progress: progress, LogLine: line, ETA: eta})
+}
+
+// Logs returns a copy of the job's log, oldest line first.
+func (j *Job) Logs() []string {
+	j.mu.RLock()
+	defer j.mu.RUnlock()
+	return slices.Clone(j.logs)
+}
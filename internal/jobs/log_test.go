@@ -0,0 +1,68 @@
+package jobs
+
+import (
+	"context"
+	"log/slog"
+	"slices"
+	"sync"
+	"testing"
+)
+
+// recordHandler keeps the records logged through it.
+type recordHandler struct {
+	mu      sync.Mutex
+	records []slog.Record
+}
+
+func (h *recordHandler) Enabled(context.Context, slog.Level) bool { return true }
+
+func (h *recordHandler) Handle(_ context.Context, r slog.Record) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.records = append(h.records, r.Clone())
+	return nil
+}
+
+func (h *recordHandler) WithAttrs([]slog.Attr) slog.Handler { return h }
+func (h *recordHandler) WithGroup(string) slog.Handler      { return h }
+
+// progressLogged returns the progress of each "job progress" record.
+func (h *recordHandler) progressLogged() []int64 {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	var progress []int64
+	for _, r := range h.records {
+		if r.Message != "job progress" || r.Level != slog.LevelDebug {
+			continue
+		}
+		r.Attrs(func(a slog.Attr) bool {
+			if a.Key == "progress" {
+				progress = append(progress, a.Value.Int64())
+			}
+			return true
+		})
+	}
+	return progress
+}
+
+func TestProgressDebugLog(t *testing.T) {
+	tests := []struct {
+		step int
+		want []int64
+	}{
+		{25, []int64{25, 50, 75, 100}},
+		{DefaultProgressLogStep, []int64{10, 20, 30, 40, 50, 60, 70, 80, 90, 100}},
+		{0, nil},
+	}
+	for _, tt := range tests {
+		records := &recordHandler{}
+		h := NewHub(slog.New(records), WithProgressLogStep(tt.step))
+		job := h.NewJob("steps", nil)
+		for p := 1; p <= 100; p++ {
+			job.SetProgress(p)
+		}
+		if got := records.progressLogged(); !slices.Equal(got, tt.want) {
+			t.Errorf("step %d: logged progress %v, want %v", tt.step, got, tt.want)
+		}
+	}
+}
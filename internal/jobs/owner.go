@@ -0,0 +1,49 @@
+package jobs
+
+import "errors"
+
+var ErrOwnerLimit = errors.New("jobs: too many active jobs for this client")
+
+// WithMaxJobsPerOwner caps how many jobs with the same Owner may be pending,
+// running or paused at once. Submitting past the cap returns ErrOwnerLimit
+// and the job is rejected without running. Zero, the default, means no cap;
+// jobs without an Owner are never capped.
+func WithMaxJobsPerOwner(n int) Option {
+	return func(h *Hub) {
+		h.maxPerOwner = max(n, 0)
+	}
+}
+
+// claimOwnerLocked counts job against its owner's cap and reports whether
+// it fits. h.mu must be held.
+func (h *Hub) claimOwnerLocked(job *Job) bool {
+	if job.Owner == "" || h.maxPerOwner <= 0 {
+		return true
+	}
+	if h.owners[job.Owner] >= h.maxPerOwner {
+		return false
+	}
+	h.owners[job.Owner]++
+	job.ownerClaimed = true
+	return true
+}
+
+// releaseOwner frees job's slot under its owner's cap once it finishes.
+func (h *Hub) releaseOwner(job *Job) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if !job.ownerClaimed {
+		return
+	}
+	job.ownerClaimed = false
+	if h.owners[job.Owner]--; h.owners[job.Owner] <= 0 {
+		delete(h.owners, job.Owner)
+	}
+}
+
+// rejectOwner finishes a job that was over its owner's cap.
+func (h *Hub) rejectOwner(job *Job) error {
+	h.logger.Warn("job limit reached for owner, rejecting job", "job_id", job.ID, "owner", job.Owner, "limit", h.maxPerOwner)
+	h.finishUnrun(job, StatusRejected, ErrOwnerLimit)
+	return ErrOwnerLimit
+}
@@ -0,0 +1,55 @@
+package jobs
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestMaxJobsPerOwner(t *testing.T) {
+	h := newTestHub(t, WithMaxJobsPerOwner(2))
+	release := make(chan struct{})
+	defer close(release)
+	submit := func(owner string) (*Job, error) {
+		job := h.NewJob("held", func(j *Job) error {
+			select {
+			case <-release:
+				return nil
+			case <-j.Context().Done():
+				return j.Context().Err()
+			}
+		})
+		job.Owner = owner
+		return job, h.Submit(job)
+	}
+
+	var held []*Job
+	for range 2 {
+		job, err := submit("alice")
+		if err != nil {
+			t.Fatalf("Submit within the cap: %v", err)
+		}
+		held = append(held, job)
+	}
+
+	extra, err := submit("alice")
+	if !errors.Is(err, ErrOwnerLimit) {
+		t.Fatalf("Submit past the cap = %v, want ErrOwnerLimit", err)
+	}
+	if got := extra.Snapshot().Status; got != StatusRejected {
+		t.Errorf("rejected job status = %q, want %q", got, StatusRejected)
+	}
+
+	// Another client is unaffected, as are jobs without an owner.
+	for _, owner := range []string{"bob", ""} {
+		if _, err := submit(owner); err != nil {
+			t.Errorf("Submit for %q: %v", owner, err)
+		}
+	}
+
+	// A finished job frees its owner's slot.
+	held[0].Cancel()
+	waitFinal(t, held[0])
+	if _, err := submit("alice"); err != nil {
+		t.Errorf("Submit after a job finished: %v", err)
+	}
+}
@@ -0,0 +1,65 @@
+package jobs
+
+// Pausing is cooperative: Pause only flags the job, and its JobFunc stops
+// at the next WaitIfPaused call. Work that never calls WaitIfPaused keeps
+// running while reported as paused.
+
+// Pause marks a running job as paused and emits an update. It reports
+// whether the job was running.
+func (j *Job) Pause() bool {
+	j.mu.Lock()
+	if j.Status != StatusRunning || j.ctx.Err() != nil {
+		j.mu.Unlock()
+		return false
+	}
+	j.Status = StatusPaused
+	j.resume = make(chan struct{})
+	progress := j.Progress
+	j.mu.Unlock()
+
+	j.emitStatus(StatusPaused, progress)
+	return true
+}
+
+// Resume lets a paused job continue and emits an update. It reports
+// whether the job was paused; resuming a cancelled or finished job is a
+// no-op.
+func (j *Job) Resume() bool {
+	j.mu.Lock()
+	if j.Status != StatusPaused || j.ctx.Err() != nil {
+		j.mu.Unlock()
+		return false
+	}
+	j.Status = StatusRunning
+	close(j.resume)
+	j.resume = nil
+	// Time spent paused would skew the rate; estimate afresh.
+	j.samples = j.samples[:0]
+	progress := j.Progress
+	j.mu.Unlock()
+
+	j.emitStatus(StatusRunning, progress)
+	return true
+}
+
+// WaitIfPaused blocks while the job is paused. It returns the context's
+// error if the job is cancelled while waiting, and nil otherwise.
+func (j *Job) WaitIfPaused() error {
+	j.mu.RLock()
+	resume := j.resume
+	j.mu.RUnlock()
+
+	if resume == nil {
+		return nil
+	}
+	select {
+	case <-resume:
+		return nil
+	case <-j.ctx.Done():
+		return j.ctx.Err()
+	}
+}
+
+func (j *Job) emitStatus(status string, progress int) {
+	j.emit(JobUpdate{Progress: progress, Status: status})
+}
@@ -0,0 +1,174 @@
+package jobs
+
+import (
+	"cmp"
+	"slices"
+	"time"
+)
+
+// Sort fields accepted in a SortKey.
+const (
+	SortCreated  = "created"
+	SortDuration = "duration"
+	SortProgress = "progress"
+	SortStatus   = "status"
+	SortName     = "name"
+)
+
+var sortComparators = map[string]func(a, b JobView) int{
+	SortCreated:  func(a, b JobView) int { return a.CreatedAt.Compare(b.CreatedAt) },
+	SortDuration: func(a, b JobView) int { return cmp.Compare(a.Duration(), b.Duration()) },
+	SortProgress: func(a, b JobView) int { return cmp.Compare(a.Progress, b.Progress) },
+	SortStatus:   func(a, b JobView) int { return cmp.Compare(a.Status, b.Status) },
+	SortName:     func(a, b JobView) int { return cmp.Compare(a.Name, b.Name) },
+}
+
+// IsSortField reports whether field can be used in a SortKey.
+func IsSortField(field string) bool {
+	_, ok := sortComparators[field]
+	return ok
+}
+
+// SortKey orders jobs by one field.
+type SortKey struct {
+	Field string
+	Desc  bool
+}
+
+// JobFilter selects, orders and pages the jobs returned by Hub.Query.
+type JobFilter struct {
+	// Status keeps only jobs with this status; empty keeps all.
+	Status string
+	// Sort orders by each key in turn; later keys break ties in earlier
+	// ones.
+	Sort []SortKey
+	// Page is 1-based.
+	Page     int
+	PageSize int
+}
+
+// JobView is a point-in-time copy of a job's fields, safe to read without
+// holding the job's lock.
+type JobView struct {
+	ID         string
+	Name       string
+	Status     string
+	Progress   int
+	CreatedAt  time.Time
+	StartedAt  time.Time
+	FinishedAt time.Time
+	Error      error
+}
+
+// Duration is how long the job ran, or has been running so far.
+func (v JobView) Duration() time.Duration {
+	switch {
+	case v.StartedAt.IsZero():
+		return 0
+	case v.FinishedAt.IsZero():
+		return time.Since(v.StartedAt)
+	default:
+		return v.FinishedAt.Sub(v.StartedAt)
+	}
+}
+
+// Snapshot returns a copy of the job's fields read under its lock.
+func (j *Job) Snapshot() JobView {
+	j.mu.RLock()
+	defer j.mu.RUnlock()
+	return JobView{
+		ID:         j.ID,
+		Name:       j.Name,
+		Status:     j.Status,
+		Progress:   j.Progress,
+		CreatedAt:  j.CreatedAt,
+		StartedAt:  j.StartedAt,
+		FinishedAt: j.FinishedAt,
+		Error:      j.Error,
+	}
+}
+
+type queryRow struct {
+	job  *Job
+	view JobView
+}
+
+// Query returns one page of jobs matching f along with the total number of
+// matches, ordered as ListSorted orders them.
+func (h *Hub) Query(f JobFilter) ([]*Job, int) {
+	rows := h.sortedRows(f.Status, f.Sort)
+
+	total := len(rows)
+	start, end := 0, total
+	if f.PageSize > 0 {
+		start = min(max(f.Page-1, 0)*f.PageSize, total)
+		end = min(start+f.PageSize, total)
+	}
+
+	page := make([]*Job, 0, end-start)
+	for _, row := range rows[start:end] {
+		page = append(page, row.job)
+	}
+	return page, total
+}
+
+// ListSorted returns every live and stored job ordered by keys, each
+// breaking ties in the ones before it. Remaining ties go to the oldest job,
+// then the lowest ID, so the order is stable across calls. Unknown fields
+// are ignored. Live jobs take precedence over their persisted copies; read
+// the returned jobs through Snapshot, since they may still be running.
+func (h *Hub) ListSorted(keys ...SortKey) []*Job {
+	rows := h.sortedRows("", keys)
+	jobs := make([]*Job, len(rows))
+	for i, row := range rows {
+		jobs[i] = row.job
+	}
+	return jobs
+}
+
+// sortedRows snapshots the jobs with the given status (all when empty) and
+// sorts them by keys. Sorting works on the snapshots, so every comparison
+// sees the same values even while jobs run.
+func (h *Hub) sortedRows(status string, keys []SortKey) []queryRow {
+	byID := make(map[string]*Job)
+
+	stored, err := h.store.List()
+	if err != nil {
+		h.logger.Error("failed to list jobs", "error", err)
+	}
+	for _, job := range stored {
+		byID[job.ID] = job
+	}
+
+	h.mu.RLock()
+	for id, job := range h.jobs {
+		byID[id] = job
+	}
+	h.mu.RUnlock()
+
+	rows := make([]queryRow, 0, len(byID))
+	for _, job := range byID {
+		view := job.Snapshot()
+		if status == "" || view.Status == status {
+			rows = append(rows, queryRow{job: job, view: view})
+		}
+	}
+
+	keys = append(slices.Clone(keys), SortKey{Field: SortCreated})
+	slices.SortStableFunc(rows, func(x, y queryRow) int {
+		for _, key := range keys {
+			compare, ok := sortComparators[key.Field]
+			if !ok {
+				continue
+			}
+			if c := compare(x.view, y.view); c != 0 {
+				if key.Desc {
+					return -c
+				}
+				return c
+			}
+		}
+		return cmp.Compare(x.view.ID, y.view.ID)
+	})
+	return rows
+}
@@ -0,0 +1,107 @@
+package jobs
+
+import (
+	"log/slog"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Run with -race: Snapshot and ListSorted read jobs while their work updates
+// them.
+func TestSnapshotWhileRunning(t *testing.T) {
+	h := newTestHub(t)
+	var jobs []*Job
+	for range 4 {
+		job := h.NewJob("busy", func(j *Job) error {
+			for i := range 1000 {
+				j.SetProgress(i % 100)
+			}
+			return nil
+		})
+		if err := h.Submit(job); err != nil {
+			t.Fatalf("Submit: %v", err)
+		}
+		jobs = append(jobs, job)
+	}
+
+	var wg sync.WaitGroup
+	done := make(chan struct{})
+	for _, job := range jobs {
+		wg.Go(func() {
+			for {
+				select {
+				case <-done:
+					return
+				default:
+				}
+				if v := job.Snapshot(); v.Progress < 0 || v.Progress > 100 {
+					t.Errorf("progress = %d, out of range", v.Progress)
+					return
+				}
+			}
+		})
+	}
+	wg.Go(func() {
+		for {
+			select {
+			case <-done:
+				return
+			default:
+			}
+			h.ListSorted(SortKey{Field: SortProgress, Desc: true})
+		}
+	})
+
+	for _, job := range jobs {
+		waitFinal(t, job)
+	}
+	close(done)
+	wg.Wait()
+
+	for _, job := range jobs {
+		if v := job.Snapshot(); v.Status != StatusCompleted || v.Progress != 100 {
+			t.Errorf("job %s = %s at %d, want completed at 100", v.ID, v.Status, v.Progress)
+		}
+	}
+}
+
+func TestListSortedTieBreak(t *testing.T) {
+	store := NewMemoryStore()
+	t0 := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	for _, rec := range []jobRecord{
+		{ID: "b", Status: StatusCompleted, CreatedAt: t0},
+		{ID: "c", Status: StatusFailed, CreatedAt: t0.Add(-time.Hour)},
+		{ID: "a", Status: StatusCompleted, CreatedAt: t0},
+		{ID: "d", Status: StatusCompleted, CreatedAt: t0.Add(-time.Hour)},
+	} {
+		if err := store.Save(rec.job()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	h := NewHub(slog.New(slog.DiscardHandler), WithStore(store))
+
+	tests := []struct {
+		name string
+		keys []SortKey
+		want []string
+	}{
+		// Equal keys fall back to the oldest job, then the lowest ID.
+		{"no keys", nil, []string{"c", "d", "a", "b"}},
+		{"status", []SortKey{{Field: SortStatus}}, []string{"d", "a", "b", "c"}},
+		{"status descending", []SortKey{{Field: SortStatus, Desc: true}}, []string{"c", "d", "a", "b"}},
+		{"unknown field ignored", []SortKey{{Field: "colour"}}, []string{"c", "d", "a", "b"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, job := range h.ListSorted(tt.keys...) {
+				got = append(got, job.ID)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("order = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,123 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// QueuePolicy decides what Submit does when the submit queue is full.
+type QueuePolicy int
+
+const (
+	// QueueReject, the default, rejects the new job at once with
+	// ErrQueueFull.
+	QueueReject QueuePolicy = iota
+	// QueueBlock waits up to the queue timeout for room, then rejects the
+	// job with ErrQueueFull. The wait also ends if the submitting context
+	// is cancelled or the hub stops.
+	QueueBlock
+	// QueueDrop makes room by rejecting the oldest queued job, so the
+	// newest work always gets in.
+	QueueDrop
+)
+
+// DefaultQueueTimeout is how long QueueBlock waits when no timeout is given.
+const DefaultQueueTimeout = 5 * time.Second
+
+func (p QueuePolicy) String() string {
+	switch p {
+	case QueueBlock:
+		return "block"
+	case QueueDrop:
+		return "drop"
+	default:
+		return "reject"
+	}
+}
+
+// ParseQueuePolicy parses "reject", "block" or "drop".
+func ParseQueuePolicy(s string) (QueuePolicy, error) {
+	for _, p := range []QueuePolicy{QueueReject, QueueBlock, QueueDrop} {
+		if s == p.String() {
+			return p, nil
+		}
+	}
+	return QueueReject, fmt.Errorf("jobs: unknown queue policy %q", s)
+}
+
+// WithQueuePolicy sets what happens when the submit queue is full. timeout
+// only applies to QueueBlock; zero or less means DefaultQueueTimeout.
+func WithQueuePolicy(policy QueuePolicy, timeout time.Duration) Option {
+	return func(h *Hub) {
+		h.queuePolicy = policy
+		h.queueTimeout = timeout
+		if timeout <= 0 {
+			h.queueTimeout = DefaultQueueTimeout
+		}
+	}
+}
+
+// enqueue hands job to the run loop, applying the queue policy when the
+// queue is full. A job that does not get in is rejected rather than
+// dropped, so its status and updates say it will never run.
+func (h *Hub) enqueue(ctx context.Context, job *Job) error {
+	if h.stopping.Load() {
+		h.finishUnrun(job, StatusRejected, ErrHubStopped)
+		return ErrHubStopped
+	}
+	select {
+	case h.submit <- job:
+		return h.queued()
+	default:
+	}
+
+	switch h.queuePolicy {
+	case QueueBlock:
+		timer := time.NewTimer(h.queueTimeout)
+		defer timer.Stop()
+		select {
+		case h.submit <- job:
+			return h.queued()
+		case <-timer.C:
+			h.logger.Warn("job queue full, rejecting job after waiting", "job_id", job.ID, "waited", h.queueTimeout)
+		case <-ctx.Done():
+			h.logger.Warn("job submitter gave up waiting for the queue", "job_id", job.ID)
+		case <-h.done:
+			h.finishUnrun(job, StatusRejected, ErrHubStopped)
+			return ErrHubStopped
+		}
+
+	case QueueDrop:
+		// Only drop while the queue is still full; the run loop may have
+		// made room in the meantime.
+		for {
+			select {
+			case h.submit <- job:
+				return h.queued()
+			default:
+			}
+			select {
+			case old := <-h.submit:
+				h.logger.Warn("job queue full, dropping oldest queued job", "job_id", old.ID, "for", job.ID)
+				h.finishUnrun(old, StatusRejected, ErrQueueFull)
+			default:
+			}
+		}
+
+	default:
+		h.logger.Warn("job queue full, rejecting job", "job_id", job.ID)
+	}
+	h.finishUnrun(job, StatusRejected, ErrQueueFull)
+	return ErrQueueFull
+}
+
+// queued is called once a job is in the submit queue. If the hub began
+// stopping after enqueue checked, nothing will take the job off the queue,
+// so it is rejected there rather than left pending.
+func (h *Hub) queued() error {
+	if h.stopping.Load() {
+		h.rejectQueued()
+	}
+	return nil
+}
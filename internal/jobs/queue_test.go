@@ -0,0 +1,88 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"testing"
+	"time"
+)
+
+// fullHub returns a hub whose run loop never started, with its submit queue
+// filled, and the queued jobs oldest first.
+func fullHub(t *testing.T, opts ...Option) (*Hub, []*Job) {
+	t.Helper()
+	h := NewHub(slog.New(slog.DiscardHandler), opts...)
+	t.Cleanup(h.Stop)
+	queued := make([]*Job, cap(h.submit))
+	for i := range queued {
+		queued[i] = h.NewJob("filler", func(*Job) error { return nil })
+		if err := h.Submit(queued[i]); err != nil {
+			t.Fatalf("filler %d: %v", i, err)
+		}
+	}
+	return h, queued
+}
+
+func TestSubmitQueueFull(t *testing.T) {
+	h, _ := fullHub(t)
+
+	job := h.NewJob("extra", func(*Job) error { return nil })
+	if err := h.Submit(job); !errors.Is(err, ErrQueueFull) {
+		t.Fatalf("Submit = %v, want ErrQueueFull", err)
+	}
+	if status := job.Snapshot().Status; status != StatusRejected {
+		t.Fatalf("status %q, want %q", status, StatusRejected)
+	}
+	if u := waitFinal(t, job); !errors.Is(u.Error, ErrQueueFull) {
+		t.Fatalf("final update error = %v, want ErrQueueFull", u.Error)
+	}
+}
+
+func TestSubmitQueueBlockTimesOut(t *testing.T) {
+	h, _ := fullHub(t, WithQueuePolicy(QueueBlock, 20*time.Millisecond))
+
+	start := time.Now()
+	if err := h.Submit(h.NewJob("extra", func(*Job) error { return nil })); !errors.Is(err, ErrQueueFull) {
+		t.Fatalf("Submit = %v, want ErrQueueFull", err)
+	}
+	if waited := time.Since(start); waited < 20*time.Millisecond {
+		t.Fatalf("rejected after %s, before the queue timeout", waited)
+	}
+}
+
+func TestSubmitQueueDropRejectsOldest(t *testing.T) {
+	h, queued := fullHub(t, WithQueuePolicy(QueueDrop, 0))
+
+	job := h.NewJob("extra", func(*Job) error { return nil })
+	if err := h.Submit(job); err != nil {
+		t.Fatalf("Submit = %v, want the newest job accepted", err)
+	}
+	if status := job.Snapshot().Status; status != StatusPending {
+		t.Fatalf("new job status %q, want pending", status)
+	}
+	if u := waitFinal(t, queued[0]); !errors.Is(u.Error, ErrQueueFull) {
+		t.Fatalf("oldest job's final error = %v, want ErrQueueFull", u.Error)
+	}
+	if n := len(h.withStatus(StatusRejected)); n != 1 {
+		t.Fatalf("%d jobs rejected, want only the oldest", n)
+	}
+}
+
+func TestStopRejectsQueued(t *testing.T) {
+	for name, stop := range map[string]func(*Hub){
+		"Stop":         (*Hub).Stop,
+		"StopGraceful": func(h *Hub) { h.StopGraceful(context.Background()) },
+	} {
+		t.Run(name, func(t *testing.T) {
+			// The run loop never takes these jobs, as after it has stopped.
+			h, queued := fullHub(t)
+			stop(h)
+			for _, job := range queued {
+				if u := waitFinal(t, job); !errors.Is(u.Error, ErrHubStopped) {
+					t.Fatalf("job %s: final error %v, want ErrHubStopped", job.ID, u.Error)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,149 @@
+package jobs
+
+import (
+	"errors"
+	"io"
+	"os"
+	"time"
+)
+
+var (
+	ErrNoResult       = errors.New("jobs: job has no result")
+	ErrResultNotReady = errors.New("jobs: job has not completed")
+)
+
+// result is a job's output, spooled to a temporary file so large outputs
+// are never held in memory.
+type result struct {
+	file        *os.File // open while the job runs
+	path        string
+	contentType string
+	filename    string
+	err         error // from creating the file
+}
+
+// ResultWriter returns a writer for the job's output, creating its backing
+// temporary file on the first call. Output can be downloaded once the job
+// completes; it is discarded if the job fails, and removed when the job is
+// reaped (see WithRetention) or the hub stops. If the file cannot be created, writes return the error, failing
+// the job when work checks it.
+func (j *Job) ResultWriter() io.Writer {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.result == nil {
+		j.result = &result{}
+	}
+	res := j.result
+	if res.file == nil && res.err == nil {
+		f, err := os.CreateTemp("", "job-result-*")
+		if err != nil {
+			res.err = err
+		} else {
+			res.file, res.path = f, f.Name()
+		}
+	}
+	if res.err != nil {
+		return errWriter{res.err}
+	}
+	return res.file
+}
+
+// resetResult empties the result before a retry, so output from the failed
+// attempt is not served.
+func (j *Job) resetResult() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.result == nil || j.result.file == nil {
+		return nil
+	}
+	if err := j.result.file.Truncate(0); err != nil {
+		return err
+	}
+	_, err := j.result.file.Seek(0, io.SeekStart)
+	return err
+}
+
+// SetResultType sets the Content-Type and download filename the result is
+// served with. Without it the type is sniffed from the content and the
+// filename is the job's name and ID.
+func (j *Job) SetResultType(contentType, filename string) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.result == nil {
+		j.result = &result{}
+	}
+	j.result.contentType, j.result.filename = contentType, filename
+}
+
+// Result is an open handle on a completed job's output. Close it when done.
+type Result struct {
+	*os.File
+	// ContentType is empty when it should be sniffed.
+	ContentType string
+	Filename    string
+	ModTime     time.Time
+}
+
+// OpenResult opens the output of a completed job. It returns ErrNoResult
+// if the job wrote none and ErrResultNotReady if it has not completed.
+func (j *Job) OpenResult() (*Result, error) {
+	j.mu.RLock()
+	status, finished, res := j.Status, j.FinishedAt, j.result
+	j.mu.RUnlock()
+
+	switch {
+	case status == StatusPending || status == StatusRunning || status == StatusPaused:
+		return nil, ErrResultNotReady
+	case status != StatusCompleted || res == nil || res.path == "":
+		return nil, ErrNoResult
+	}
+
+	f, err := os.Open(res.path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, ErrNoResult
+		}
+		return nil, err
+	}
+	filename := res.filename
+	if filename == "" {
+		filename = j.Name + "-" + j.ID
+	}
+	return &Result{File: f, ContentType: res.contentType, Filename: filename, ModTime: finished}, nil
+}
+
+// finishResult closes the result file once work returns, removing it
+// unless the job completed.
+func (j *Job) finishResult() {
+	j.mu.Lock()
+	res, completed := j.result, j.Status == StatusCompleted
+	j.mu.Unlock()
+	if res == nil || res.file == nil {
+		return
+	}
+
+	if err := res.file.Close(); err != nil {
+		j.logger.Error("failed to close job result", "job_id", j.ID, "error", err)
+	}
+	if !completed {
+		j.removeResult()
+	}
+}
+
+// removeResult deletes the result file, if any. Open Results stay readable
+// until closed.
+func (j *Job) removeResult() {
+	j.mu.Lock()
+	res := j.result
+	j.mu.Unlock()
+	if res == nil || res.path == "" {
+		return
+	}
+	if err := os.Remove(res.path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		j.logger.Error("failed to remove job result", "job_id", j.ID, "error", err)
+	}
+}
+
+type errWriter struct{ err error }
+
+func (w errWriter) Write([]byte) (int, error) { return 0, w.err }
@@ -0,0 +1,63 @@
+package jobs
+
+import "time"
+
+// reapInterval caps how often Run looks for jobs past their retention.
+const reapInterval = time.Minute
+
+// WithRetention drops finished jobs from the hub d after they finish and
+// deletes their result files, so a long-running server doesn't keep every
+// job, and its output, forever. Stores with a Delete method, such as
+// MemoryStore, forget the job too; others, such as the SQLite store, keep
+// its record as history. Zero, the default, keeps finished jobs until Stop.
+func WithRetention(d time.Duration) Option {
+	return func(h *Hub) {
+		h.retention = max(d, 0)
+	}
+}
+
+// reapLoop removes expired jobs until Stop is called.
+func (h *Hub) reapLoop() {
+	ticker := time.NewTicker(min(h.retention, reapInterval))
+	defer ticker.Stop()
+	for {
+		select {
+		case now := <-ticker.C:
+			h.reap(now)
+		case <-h.done:
+			return
+		}
+	}
+}
+
+// reap removes the jobs that finished at least the retention period before
+// now, deletes their result files and returns how many it removed.
+func (h *Hub) reap(now time.Time) int {
+	var expired []*Job
+	h.mu.Lock()
+	for id, job := range h.jobs {
+		finished := job.Snapshot().FinishedAt
+		if finished.IsZero() || now.Sub(finished) < h.retention {
+			continue
+		}
+		delete(h.jobs, id)
+		delete(h.dependsOn, id)
+		expired = append(expired, job)
+	}
+	h.mu.Unlock()
+
+	deleter, _ := h.store.(interface{ Delete(id string) error })
+	for _, job := range expired {
+		job.removeResult()
+		if deleter == nil {
+			continue
+		}
+		if err := deleter.Delete(job.ID); err != nil {
+			h.logger.Error("failed to delete reaped job", "job_id", job.ID, "error", err)
+		}
+	}
+	if len(expired) > 0 {
+		h.logger.Debug("reaped finished jobs", "count", len(expired), "retention", h.retention)
+	}
+	return len(expired)
+}
@@ -0,0 +1,58 @@
+package jobs
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestReapRemovesFinishedJobs(t *testing.T) {
+	h := newTestHub(t, WithRetention(time.Hour))
+
+	job := h.NewJob("report", func(j *Job) error {
+		_, err := fmt.Fprintln(j.ResultWriter(), "a,b")
+		return err
+	})
+	if err := h.Submit(job); err != nil {
+		t.Fatal(err)
+	}
+	waitFinal(t, job)
+	path := job.result.path
+	if _, err := os.Stat(path); err != nil {
+		t.Fatalf("result file: %v", err)
+	}
+
+	finished := job.Snapshot().FinishedAt
+	if n := h.reap(finished.Add(time.Minute)); n != 0 {
+		t.Fatalf("reaped %d jobs before the retention period", n)
+	}
+	if n := h.reap(finished.Add(time.Hour)); n != 1 {
+		t.Fatalf("reaped %d jobs, want 1", n)
+	}
+	if _, ok := h.Get(job.ID); ok {
+		t.Fatal("reaped job still found")
+	}
+	if _, err := os.Stat(path); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("result file after reaping: %v, want it removed", err)
+	}
+}
+
+func TestReapKeepsUnfinishedJobs(t *testing.T) {
+	h := newTestHub(t, WithRetention(time.Nanosecond))
+
+	started := make(chan struct{})
+	job := h.NewJob("long", func(j *Job) error {
+		close(started)
+		<-j.Context().Done()
+		return j.Context().Err()
+	})
+	if err := h.Submit(job); err != nil {
+		t.Fatal(err)
+	}
+	<-started
+	if n := h.reap(time.Now().Add(time.Hour)); n != 0 {
+		t.Fatalf("reaped %d running jobs", n)
+	}
+}
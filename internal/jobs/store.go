@@ -0,0 +1,110 @@
+package jobs
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+var ErrJobNotFound = errors.New("jobs: job not found")
+
+// JobStore persists job metadata. Work functions and update channels are
+// never persisted; jobs loaded from a store are read-only records.
+type JobStore interface {
+	Save(job *Job) error
+	Load(id string) (*Job, error)
+	List() ([]*Job, error)
+	UpdateStatus(id, status string, progress int, jobErr error) error
+}
+
+// MemoryStore is the default JobStore. Its contents are lost on restart.
+type MemoryStore struct {
+	jobs map[string]*Job
+	mu   sync.RWMutex
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{jobs: make(map[string]*Job)}
+}
+
+func (s *MemoryStore) Save(job *Job) error {
+	s.mu.Lock()
+	s.jobs[job.ID] = job
+	s.mu.Unlock()
+	return nil
+}
+
+func (s *MemoryStore) Load(id string) (*Job, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	job, ok := s.jobs[id]
+	if !ok {
+		return nil, ErrJobNotFound
+	}
+	return job, nil
+}
+
+func (s *MemoryStore) List() ([]*Job, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	jobs := make([]*Job, 0, len(s.jobs))
+	for _, job := range s.jobs {
+		jobs = append(jobs, job)
+	}
+	return jobs, nil
+}
+
+// Delete forgets the job. The hub calls it when it reaps a job (see
+// WithRetention), since this store holds the live job itself.
+func (s *MemoryStore) Delete(id string) error {
+	s.mu.Lock()
+	delete(s.jobs, id)
+	s.mu.Unlock()
+	return nil
+}
+
+// UpdateStatus is a no-op beyond existence checking: the stored *Job is the
+// live job, which the hub has already updated.
+func (s *MemoryStore) UpdateStatus(id, status string, progress int, jobErr error) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if _, ok := s.jobs[id]; !ok {
+		return ErrJobNotFound
+	}
+	return nil
+}
+
+// jobRecord holds the persisted fields of a job.
+type jobRecord struct {
+	ID         string
+	Name       string
+	Status     string
+	Progress   int
+	Error      string
+	CreatedAt  time.Time
+	StartedAt  time.Time
+	FinishedAt time.Time
+}
+
+// job builds a finished, read-only job from the record. Its update channel
+// is already closed so streaming it ends immediately.
+func (rec jobRecord) job() *Job {
+	updates := make(chan JobUpdate)
+	close(updates)
+
+	job := &Job{
+		ID:         rec.ID,
+		Name:       rec.Name,
+		Status:     rec.Status,
+		Progress:   rec.Progress,
+		CreatedAt:  rec.CreatedAt,
+		StartedAt:  rec.StartedAt,
+		FinishedAt: rec.FinishedAt,
+		cancel:     func() {},
+		updates:    updates,
+	}
+	if rec.Error != "" {
+		job.Error = errors.New(rec.Error)
+	}
+	return job
+}
@@ -0,0 +1,10 @@
+//go:build !sqlite
+
+package jobs
+
+import "errors"
+
+// OpenSQLiteStore is unavailable in builds without the sqlite tag.
+func OpenSQLiteStore(path string) (JobStore, error) {
+	return nil, errors.New("jobs: SQLite support not compiled in; rebuild with -tags sqlite")
+}
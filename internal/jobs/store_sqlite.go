@@ -0,0 +1,145 @@
+//go:build sqlite
+
+package jobs
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	_ "modernc.org/sqlite"
+)
+
+const sqliteSchema = `CREATE TABLE IF NOT EXISTS jobs (
+	id         TEXT PRIMARY KEY,
+	name       TEXT NOT NULL,
+	status     TEXT NOT NULL,
+	progress   INTEGER NOT NULL DEFAULT 0,
+	error      TEXT NOT NULL DEFAULT '',
+	created_at INTEGER NOT NULL,
+	started_at INTEGER NOT NULL DEFAULT 0,
+	finished_at INTEGER NOT NULL DEFAULT 0
+)`
+
+const sqliteColumns = `id, name, status, progress, error, created_at, started_at, finished_at`
+
+// SQLiteStore persists job metadata in a SQLite database so it survives
+// restarts. Build with -tags sqlite to enable it.
+type SQLiteStore struct {
+	db *sql.DB
+}
+
+func OpenSQLiteStore(path string) (JobStore, error) {
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return nil, err
+	}
+	// SQLite allows a single writer; serialise access through one connection.
+	db.SetMaxOpenConns(1)
+
+	if _, err := db.Exec(sqliteSchema); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &SQLiteStore{db: db}, nil
+}
+
+func (s *SQLiteStore) Save(job *Job) error {
+	job.mu.RLock()
+	defer job.mu.RUnlock()
+
+	_, err := s.db.Exec(
+		`INSERT INTO jobs (`+sqliteColumns+`) VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(id) DO UPDATE SET status = excluded.status, progress = excluded.progress, error = excluded.error,
+			started_at = excluded.started_at, finished_at = excluded.finished_at`,
+		job.ID, job.Name, job.Status, job.Progress, errString(job.Error),
+		unixNano(job.CreatedAt), unixNano(job.StartedAt), unixNano(job.FinishedAt),
+	)
+	return err
+}
+
+func (s *SQLiteStore) Load(id string) (*Job, error) {
+	row := s.db.QueryRow(`SELECT `+sqliteColumns+` FROM jobs WHERE id = ?`, id)
+	job, err := scanJob(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrJobNotFound
+	}
+	return job, err
+}
+
+func (s *SQLiteStore) List() ([]*Job, error) {
+	rows, err := s.db.Query(`SELECT ` + sqliteColumns + ` FROM jobs ORDER BY created_at`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var jobs []*Job
+	for rows.Next() {
+		job, err := scanJob(rows)
+		if err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, job)
+	}
+	return jobs, rows.Err()
+}
+
+func (s *SQLiteStore) UpdateStatus(id, status string, progress int, jobErr error) error {
+	res, err := s.db.Exec(
+		`UPDATE jobs SET status = ?, progress = ?, error = ? WHERE id = ?`,
+		status, progress, errString(jobErr), id,
+	)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrJobNotFound
+	}
+	return nil
+}
+
+func (s *SQLiteStore) Close() error {
+	return s.db.Close()
+}
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func scanJob(row scanner) (*Job, error) {
+	var (
+		rec                              jobRecord
+		createdAt, startedAt, finishedAt int64
+	)
+	err := row.Scan(&rec.ID, &rec.Name, &rec.Status, &rec.Progress, &rec.Error, &createdAt, &startedAt, &finishedAt)
+	if err != nil {
+		return nil, err
+	}
+	rec.CreatedAt = fromUnixNano(createdAt)
+	rec.StartedAt = fromUnixNano(startedAt)
+	rec.FinishedAt = fromUnixNano(finishedAt)
+	return rec.job(), nil
+}
+
+// unixNano stores the zero time as 0 rather than a large negative number.
+func unixNano(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.UnixNano()
+}
+
+func fromUnixNano(n int64) time.Time {
+	if n == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, n)
+}
+
+func errString(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
@@ -0,0 +1,56 @@
+package jobs
+
+import "time"
+
+// WithProgressInterval sets the default minimum time between progress
+// updates a job emits (see Job.SetProgressInterval). Zero, the default,
+// emits every SetProgress call.
+func WithProgressInterval(d time.Duration) Option {
+	return func(h *Hub) {
+		h.progressInterval = max(d, 0)
+	}
+}
+
+// SetProgressInterval makes SetProgress emit at most one update per d,
+// overriding the hub's default. Values in between are coalesced: the latest
+// is sent once d has passed, so the bar never stalls behind the job. The
+// terminal update is never held back. It must be called before the job is
+// submitted.
+func (j *Job) SetProgressInterval(d time.Duration) {
+	j.progressInterval = max(d, 0)
+}
+
+// throttled reports whether a progress update at now must wait, and if so
+// makes sure a trailing update is scheduled. The caller must hold j.mu.
+func (j *Job) throttled(now time.Time) bool {
+	if j.progressInterval <= 0 {
+		return false
+	}
+	if wait := j.progressInterval - now.Sub(j.progressSent); wait > 0 {
+		if !j.flushPending {
+			j.flushPending = true
+			time.AfterFunc(wait, j.flushProgress)
+		}
+		return true
+	}
+	j.progressSent = now
+	return false
+}
+
+// flushProgress emits the latest progress held back by throttled, unless
+// the job has stopped running in the meantime: its status update already
+// carries the final value and must stay last.
+func (j *Job) flushProgress() {
+	j.mu.Lock()
+	j.flushPending = false
+	if j.closed || j.Status != StatusRunning {
+		j.mu.Unlock()
+		return
+	}
+	now := time.Now()
+	j.progressSent = now
+	u := JobUpdate{Progress: j.Progress, ETA: estimateETA(j.samples, now)}
+	j.mu.Unlock()
+
+	j.emit(u)
+}
@@ -0,0 +1,49 @@
+package jobs
+
+import (
+	"testing"
+	"time"
+)
+
+func TestProgressBurstIsThrottled(t *testing.T) {
+	const interval = 50 * time.Millisecond
+	h := newTestHub(t)
+
+	var burst time.Duration
+	job := h.NewJob("burst", func(j *Job) error {
+		start := time.Now()
+		for i := range 1000 {
+			j.SetProgress(i / 10)
+		}
+		burst = time.Since(start)
+		// Outlast the interval so the held-back last value is flushed.
+		time.Sleep(3 * interval)
+		return nil
+	})
+	job.SetProgressInterval(interval)
+	if err := h.Submit(job); err != nil {
+		t.Fatalf("Submit: %v", err)
+	}
+
+	var progress []int
+	var final JobUpdate
+	for u := range job.Updates() {
+		if u.Done {
+			final = u
+			continue
+		}
+		progress = append(progress, u.Progress)
+	}
+
+	// One update per interval during the burst, plus the leading and
+	// trailing ones.
+	if limit := int(burst/interval) + 2; len(progress) > limit {
+		t.Errorf("%d progress updates in a %v burst, want at most %d: %v", len(progress), burst, limit, progress)
+	}
+	if len(progress) == 0 || progress[len(progress)-1] != 99 {
+		t.Errorf("progress updates = %v, want the last to be 99", progress)
+	}
+	if !final.Done || final.Error != nil || final.Progress != 100 {
+		t.Errorf("final update = %+v, want success at 100", final)
+	}
+}
@@ -0,0 +1,59 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+)
+
+// watchdogGrace is how long a job's work may keep running after its
+// deadline cancelled its context before the hub gives up on it. It is a
+// variable so tests can shorten it.
+var watchdogGrace = 5 * time.Second
+
+// SetTimeout gives the job d to finish, counting retries and time spent
+// paused. At the deadline its context is cancelled; work that ignores the
+// cancellation for watchdogGrace more is abandoned and the job fails with
+// context.DeadlineExceeded. It must be called before the job is submitted.
+func (j *Job) SetTimeout(d time.Duration) {
+	j.timeout = max(d, 0)
+}
+
+// runWatched runs the job's work, enforcing its timeout if it has one.
+// Abandoned work keeps its goroutine, which can't be stopped from outside,
+// but its further updates are discarded.
+func (h *Hub) runWatched(job *Job, logger *slog.Logger) error {
+	if job.timeout <= 0 {
+		return h.runWithRetries(job)
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- h.runWithRetries(job)
+	}()
+
+	deadline := time.NewTimer(job.timeout)
+	defer deadline.Stop()
+	select {
+	case err := <-done:
+		return err
+	case <-deadline.C:
+	}
+
+	job.cancel()
+	grace := time.NewTimer(watchdogGrace)
+	defer grace.Stop()
+	select {
+	case err := <-done:
+		// The work noticed the cancellation the deadline caused.
+		if errors.Is(err, context.Canceled) {
+			return context.DeadlineExceeded
+		}
+		return err
+	case <-grace.C:
+		logger.Warn("job ignored its cancellation past its deadline; abandoning it, fix its work to return when its context is done",
+			"job_id", job.ID, "name", job.Name, "timeout", job.timeout)
+		return context.DeadlineExceeded
+	}
+}
@@ -0,0 +1,42 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestWatchdogAbandonsUncooperativeWork(t *testing.T) {
+	defer func(d time.Duration) { watchdogGrace = d }(watchdogGrace)
+	watchdogGrace = 50 * time.Millisecond
+
+	h := newTestHub(t, WithWorkers(1))
+	stuck := make(chan struct{})
+	defer close(stuck)
+
+	// The work ignores its context, so only the watchdog can end the job.
+	job := h.NewJob("stubborn", func(*Job) error {
+		<-stuck
+		return nil
+	})
+	job.SetTimeout(20 * time.Millisecond)
+	if err := h.Submit(job); err != nil {
+		t.Fatalf("Submit: %v", err)
+	}
+	if u := waitFinal(t, job); !errors.Is(u.Error, context.DeadlineExceeded) {
+		t.Fatalf("final error = %v, want DeadlineExceeded", u.Error)
+	}
+	if got := job.Snapshot().Status; got != StatusFailed {
+		t.Errorf("status = %q, want %q", got, StatusFailed)
+	}
+
+	// The abandoned work still blocks, but the only worker is free again.
+	next := h.NewJob("next", func(*Job) error { return nil })
+	if err := h.Submit(next); err != nil {
+		t.Fatalf("Submit: %v", err)
+	}
+	if u := waitFinal(t, next); u.Error != nil {
+		t.Fatalf("next job failed: %v", u.Error)
+	}
+}
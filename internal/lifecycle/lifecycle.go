@@ -0,0 +1,63 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+type Hook func(ctx context.Context) error
+
+// ErrShuttingDown is the cause given to request contexts cancelled because
+// the server is shutting down, so handlers can tell it from a client that
+// went away (see context.Cause).
+var ErrShuttingDown = errors.New("server shutting down")
+
+type namedHook struct {
+	name string
+	fn   Hook
+}
+
+// Registry collects shutdown hooks from components as they are created and
+// runs them in reverse registration order, so dependents stop before the
+// things they depend on.
+type Registry struct {
+	hooks  []namedHook
+	logger *slog.Logger
+	mu     sync.Mutex
+}
+
+func New(logger *slog.Logger) *Registry {
+	return &Registry{logger: logger}
+}
+
+func (r *Registry) OnShutdown(name string, fn Hook) {
+	r.mu.Lock()
+	r.hooks = append(r.hooks, namedHook{name: name, fn: fn})
+	r.mu.Unlock()
+}
+
+// Shutdown runs every hook, last registered first, within ctx. A failing
+// hook is logged and does not prevent the remaining hooks from running.
+func (r *Registry) Shutdown(ctx context.Context) error {
+	r.mu.Lock()
+	hooks := make([]namedHook, len(r.hooks))
+	copy(hooks, r.hooks)
+	r.mu.Unlock()
+
+	var errs []error
+	for i := len(hooks) - 1; i >= 0; i-- {
+		hook := hooks[i]
+		start := time.Now()
+		if err := hook.fn(ctx); err != nil {
+			r.logger.Error("shutdown hook failed", "hook", hook.name, "error", err, "duration", time.Since(start))
+			errs = append(errs, fmt.Errorf("%s: %w", hook.name, err))
+			continue
+		}
+		r.logger.Info("shutdown hook completed", "hook", hook.name, "duration", time.Since(start))
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,42 @@
+package middleware
+
+import (
+	"net/http"
+	"path"
+	"strings"
+)
+
+// CleanPath redirects requests whose path ends in a redundant slash, such
+// as /api/jobs/, to the path without it, so they reach the route instead of
+// falling through to a 404. The root and paths starting with one of keep,
+// such as the static file subtree, are left alone. GET and HEAD get a 301;
+// other methods get a 308 so the method and body survive the redirect.
+func CleanPath(keep ...string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			p := r.URL.Path
+			// Browsers don't follow redirects on CORS preflights.
+			if p == "/" || !strings.HasSuffix(p, "/") || r.Method == http.MethodOptions {
+				next.ServeHTTP(w, r)
+				return
+			}
+			for _, prefix := range keep {
+				if strings.HasPrefix(p, prefix) {
+					next.ServeHTTP(w, r)
+					return
+				}
+			}
+
+			// path.Clean also collapses leading slashes, so //host/ can't
+			// turn into a protocol-relative redirect to another site.
+			u := *r.URL
+			u.Path = path.Clean(p)
+			u.RawPath = ""
+			code := http.StatusMovedPermanently
+			if r.Method != http.MethodGet && r.Method != http.MethodHead {
+				code = http.StatusPermanentRedirect
+			}
+			http.Redirect(w, r, u.RequestURI(), code)
+		})
+	}
+}
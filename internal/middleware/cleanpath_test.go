@@ -0,0 +1,38 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCleanPath(t *testing.T) {
+	handler := CleanPath("/static/")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	tests := []struct {
+		method, target string
+		wantStatus     int
+		wantLocation   string
+	}{
+		{http.MethodGet, "/demo/", http.StatusMovedPermanently, "/demo"},
+		{http.MethodGet, "/demo/?tab=2", http.StatusMovedPermanently, "/demo?tab=2"},
+		{http.MethodPost, "/api/jobs/", http.StatusPermanentRedirect, "/api/jobs"},
+		{http.MethodGet, "//evil.example/", http.StatusMovedPermanently, "/evil.example"},
+		{http.MethodGet, "/demo", http.StatusNoContent, ""},
+		{http.MethodGet, "/", http.StatusNoContent, ""},
+		{http.MethodGet, "/static/css/", http.StatusNoContent, ""},
+		{http.MethodOptions, "/api/jobs/", http.StatusNoContent, ""},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(tt.method, tt.target, nil))
+		if rec.Code != tt.wantStatus {
+			t.Errorf("%s %s: status %d, want %d", tt.method, tt.target, rec.Code, tt.wantStatus)
+		}
+		if got := rec.Header().Get("Location"); got != tt.wantLocation {
+			t.Errorf("%s %s: Location %q, want %q", tt.method, tt.target, got, tt.wantLocation)
+		}
+	}
+}
@@ -0,0 +1,44 @@
+package middleware
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+
+	"github.com/ankit-lilly/go-datastar-daisyui-template/internal/views"
+)
+
+// cspPolicy allows scripts only from this origin or carrying the nonce.
+// Datastar evaluates its data-* expressions with Function, hence
+// 'unsafe-eval'; inline <script> elements still need the nonce.
+const cspPolicy = "default-src 'self'; " +
+	"script-src 'self' 'nonce-%s' 'unsafe-eval'; " +
+	"style-src 'self' 'unsafe-inline'; " +
+	"img-src 'self' data:; " +
+	"connect-src 'self'; " +
+	"object-src 'none'; " +
+	"base-uri 'self'; " +
+	"form-action 'self'; " +
+	"frame-ancestors 'none'"
+
+// CSP sets a Content-Security-Policy with a script nonce and puts the nonce
+// in the request context, where views.Nonce reads it for the layout's
+// <script> tags.
+//
+// The nonce is drawn fresh for every response, so a page carrying one must
+// not be revalidated from a cache: its nonce would no longer match the
+// header. Handlers serve such pages with Cache-Control: no-store.
+func CSP(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		nonce := cspNonce()
+		w.Header().Set("Content-Security-Policy", fmt.Sprintf(cspPolicy, nonce))
+		next.ServeHTTP(w, r.WithContext(views.WithNonce(r.Context(), nonce)))
+	})
+}
+
+func cspNonce() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
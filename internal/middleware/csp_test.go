@@ -0,0 +1,39 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/ankit-lilly/go-datastar-daisyui-template/internal/views"
+)
+
+var headerNonce = regexp.MustCompile(`'nonce-([^']+)'`)
+
+func TestCSPNonceMatchesHTML(t *testing.T) {
+	page := views.Layout("Test", views.ErrorPage(http.StatusNotFound, "missing"))
+	handler := CSP(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		page.Render(r.Context(), w)
+	}))
+
+	seen := make(map[string]bool)
+	for i := range 2 {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+		m := headerNonce.FindStringSubmatch(rec.Header().Get("Content-Security-Policy"))
+		if m == nil {
+			t.Fatalf("response %d: no nonce in %q", i, rec.Header().Get("Content-Security-Policy"))
+		}
+		nonce := m[1]
+		if !strings.Contains(rec.Body.String(), `nonce="`+nonce+`"`) {
+			t.Fatalf("response %d: header nonce %q not in the page", i, nonce)
+		}
+		if seen[nonce] {
+			t.Fatalf("response %d: nonce %q reused", i, nonce)
+		}
+		seen[nonce] = true
+	}
+}
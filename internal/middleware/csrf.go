@@ -0,0 +1,89 @@
+package middleware
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"net/http"
+	"strings"
+
+	"github.com/ankit-lilly/go-datastar-daisyui-template/internal/util"
+	"github.com/ankit-lilly/go-datastar-daisyui-template/internal/views"
+)
+
+const (
+	CSRFCookieName = "csrf_token"
+	CSRFHeaderName = "X-CSRF-Token"
+)
+
+// CSRF implements signed double-submit cookie protection. Every request
+// gets a token cookie (issued on first visit) which is also placed in the
+// request context for templates. Tokens are signed with secret, so a cookie
+// planted by a sibling subdomain is not accepted. Unsafe methods must echo
+// the token back in the X-CSRF-Token header; safe methods, including SSE GET
+// streams, pass through.
+func CSRF(secret []byte) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return csrf(secret, next)
+	}
+}
+
+func csrf(secret []byte, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var token string
+		if c, err := r.Cookie(CSRFCookieName); err == nil && validCSRFToken(secret, c.Value) {
+			token = c.Value
+		}
+
+		if !isSafeMethod(r.Method) {
+			header := r.Header.Get(CSRFHeaderName)
+			if token == "" || subtle.ConstantTimeCompare([]byte(header), []byte(token)) != 1 {
+				http.Error(w, "Forbidden - invalid CSRF token", http.StatusForbidden)
+				return
+			}
+		}
+
+		if token == "" {
+			token = newCSRFToken(secret)
+			http.SetCookie(w, &http.Cookie{
+				Name:     CSRFCookieName,
+				Value:    token,
+				Path:     "/",
+				HttpOnly: true,
+				Secure:   r.TLS != nil,
+				SameSite: http.SameSiteLaxMode,
+			})
+		}
+
+		next.ServeHTTP(w, r.WithContext(views.WithCSRFToken(r.Context(), token)))
+	})
+}
+
+// newCSRFToken returns a random ID and its signature, joined by a dot.
+func newCSRFToken(secret []byte) string {
+	id := util.GenerateID()
+	return id + "." + signCSRF(secret, id)
+}
+
+func validCSRFToken(secret []byte, token string) bool {
+	id, sig, ok := strings.Cut(token, ".")
+	if !ok || id == "" {
+		return false
+	}
+	return hmac.Equal([]byte(sig), []byte(signCSRF(secret, id)))
+}
+
+func signCSRF(secret []byte, id string) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(id))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func isSafeMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,104 @@
+package middleware
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/ankit-lilly/go-datastar-daisyui-template/internal/assets"
+)
+
+// Gzip compresses text responses on the fly at level (gzip.BestSpeed to
+// gzip.BestCompression, or gzip.DefaultCompression) for clients that accept
+// gzip. Responses that already carry a Content-Encoding (such as
+// precompressed static files), partial or non-200 responses, and HEAD
+// requests pass through untouched. Writers are pooled, since each one
+// allocates several hundred kilobytes of compressor state.
+func Gzip(level int) func(http.Handler) http.Handler {
+	pool := &sync.Pool{
+		New: func() any {
+			gz, err := gzip.NewWriterLevel(io.Discard, level)
+			if err != nil {
+				// Config validation rejects bad levels before we get here.
+				panic(err)
+			}
+			return gz
+		},
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method == http.MethodHead || !assets.AcceptsEncoding(r, "gzip") {
+				next.ServeHTTP(w, r)
+				return
+			}
+			gw := &gzipWriter{ResponseWriter: w, pool: pool}
+			defer gw.close()
+			next.ServeHTTP(gw, r)
+		})
+	}
+}
+
+type gzipWriter struct {
+	http.ResponseWriter
+	gz          *gzip.Writer
+	pool        *sync.Pool
+	wroteHeader bool
+}
+
+func (w *gzipWriter) WriteHeader(code int) {
+	if w.wroteHeader {
+		return
+	}
+	w.wroteHeader = true
+
+	h := w.Header()
+	if code == http.StatusOK && h.Get("Content-Encoding") == "" && compressible(h.Get("Content-Type")) {
+		h.Set("Content-Encoding", "gzip")
+		if !slices.Contains(h.Values("Vary"), "Accept-Encoding") {
+			h.Add("Vary", "Accept-Encoding")
+		}
+		h.Del("Content-Length")
+		w.gz = w.pool.Get().(*gzip.Writer)
+		w.gz.Reset(w.ResponseWriter)
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *gzipWriter) Write(b []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	if w.gz != nil {
+		return w.gz.Write(b)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// Unwrap exposes the underlying writer to http.ResponseController.
+func (w *gzipWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+func (w *gzipWriter) close() {
+	if w.gz != nil {
+		w.gz.Close()
+		w.gz.Reset(io.Discard)
+		w.pool.Put(w.gz)
+		w.gz = nil
+	}
+}
+
+func compressible(contentType string) bool {
+	mediaType, _, _ := strings.Cut(contentType, ";")
+	switch mediaType = strings.TrimSpace(mediaType); {
+	case strings.HasPrefix(mediaType, "text/"):
+		return true
+	case mediaType == "application/javascript", mediaType == "application/json", mediaType == "image/svg+xml":
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,85 @@
+package middleware
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+var gzipBody = strings.Repeat("<p>Hello from the template.</p>\n", 200)
+
+func textHandler(contentType string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", contentType)
+		io.WriteString(w, gzipBody)
+	})
+}
+
+func gzipRequest() *http.Request {
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("Accept-Encoding", "gzip")
+	return r
+}
+
+func TestGzipCompressesText(t *testing.T) {
+	handler := Gzip(gzip.BestSpeed)(textHandler("text/html; charset=utf-8"))
+
+	// Twice, so the second response reuses a pooled writer.
+	for i := range 2 {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, gzipRequest())
+		if got := rec.Header().Get("Content-Encoding"); got != "gzip" {
+			t.Fatalf("response %d: Content-Encoding %q, want gzip", i, got)
+		}
+		zr, err := gzip.NewReader(rec.Body)
+		if err != nil {
+			t.Fatalf("response %d: %v", i, err)
+		}
+		body, err := io.ReadAll(zr)
+		if err != nil {
+			t.Fatalf("response %d: %v", i, err)
+		}
+		if string(body) != gzipBody {
+			t.Fatalf("response %d: body does not round-trip", i)
+		}
+	}
+}
+
+func TestGzipSkipsBinary(t *testing.T) {
+	rec := httptest.NewRecorder()
+	Gzip(gzip.DefaultCompression)(textHandler("image/png")).ServeHTTP(rec, gzipRequest())
+	if got := rec.Header().Get("Content-Encoding"); got != "" {
+		t.Fatalf("Content-Encoding %q, want none", got)
+	}
+	if rec.Body.String() != gzipBody {
+		t.Fatal("body changed")
+	}
+}
+
+// BenchmarkGzip compares the pooled middleware with a fresh writer per
+// response, which is what the pool saves.
+func BenchmarkGzip(b *testing.B) {
+	b.Run("pooled", func(b *testing.B) {
+		handler := Gzip(gzip.DefaultCompression)(textHandler("text/html"))
+		b.ReportAllocs()
+		for b.Loop() {
+			handler.ServeHTTP(httptest.NewRecorder(), gzipRequest())
+		}
+	})
+	b.Run("unpooled", func(b *testing.B) {
+		handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "text/html")
+			w.Header().Set("Content-Encoding", "gzip")
+			gz, _ := gzip.NewWriterLevel(w, gzip.DefaultCompression)
+			io.WriteString(gz, gzipBody)
+			gz.Close()
+		})
+		b.ReportAllocs()
+		for b.Loop() {
+			handler.ServeHTTP(httptest.NewRecorder(), gzipRequest())
+		}
+	})
+}
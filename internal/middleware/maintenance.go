@@ -0,0 +1,113 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/ankit-lilly/go-datastar-daisyui-template/internal/views"
+)
+
+// ErrMaintenance is the cause given to Datastar request contexts ended
+// because maintenance mode turned on (see context.Cause).
+var ErrMaintenance = errors.New("maintenance mode on")
+
+// MaintenanceMode is a switch that, while on, answers requests with a 503
+// "be right back" page. Turning it on also ends open Datastar streams, so
+// clients don't stay attached to a server that is about to go away.
+type MaintenanceMode struct {
+	on atomic.Bool
+
+	mu sync.Mutex
+	// entered is closed, and replaced, each time maintenance turns on.
+	entered chan struct{}
+}
+
+func NewMaintenanceMode() *MaintenanceMode {
+	return &MaintenanceMode{entered: make(chan struct{})}
+}
+
+// Enabled reports whether maintenance mode is on.
+func (m *MaintenanceMode) Enabled() bool {
+	return m.on.Load()
+}
+
+// Set turns maintenance mode on or off.
+func (m *MaintenanceMode) Set(on bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.setLocked(on)
+}
+
+// Toggle flips maintenance mode and returns the new state.
+func (m *MaintenanceMode) Toggle() bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	on := !m.on.Load()
+	m.setLocked(on)
+	return on
+}
+
+func (m *MaintenanceMode) setLocked(on bool) {
+	if m.on.Swap(on) == on || !on {
+		return
+	}
+	close(m.entered)
+	m.entered = make(chan struct{})
+}
+
+func (m *MaintenanceMode) enteredCh() <-chan struct{} {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.entered
+}
+
+// Middleware answers 503 with Retry-After while maintenance is on, except
+// for paths starting with one of exempt, such as health checks, static
+// files and the endpoint that turns maintenance off. Browsers get the
+// DaisyUI maintenance page; Datastar requests get plain text. Datastar
+// requests let through get a context that ends, with the cause
+// ErrMaintenance, when maintenance turns on.
+func (m *MaintenanceMode) Middleware(exempt ...string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			for _, prefix := range exempt {
+				if strings.HasPrefix(r.URL.Path, prefix) {
+					next.ServeHTTP(w, r)
+					return
+				}
+			}
+
+			datastar := r.Header.Get("Datastar-Request") == "true"
+			if m.Enabled() {
+				w.Header().Set("Retry-After", "60")
+				if datastar {
+					http.Error(w, "Service Unavailable: down for maintenance", http.StatusServiceUnavailable)
+					return
+				}
+				w.Header().Set("Content-Type", "text/html; charset=utf-8")
+				w.WriteHeader(http.StatusServiceUnavailable)
+				views.MaintenancePage().Render(r.Context(), w)
+				return
+			}
+
+			if datastar {
+				ctx, cancel := context.WithCancelCause(r.Context())
+				defer cancel(nil)
+				entered := m.enteredCh()
+				go func() {
+					select {
+					case <-entered:
+						cancel(ErrMaintenance)
+					case <-ctx.Done():
+					}
+				}()
+				r = r.WithContext(ctx)
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,50 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// BodyLimit overrides the MaxBody limit for requests whose path starts with
+// Prefix. A Limit of zero or less removes the limit, e.g. for uploads that
+// enforce their own.
+type BodyLimit struct {
+	Prefix string
+	Limit  int64
+}
+
+// MaxBody caps request bodies at limit bytes, or at the limit of the
+// longest matching override. Requests declaring a larger Content-Length
+// are answered 413 up front; otherwise the body is wrapped in
+// http.MaxBytesReader, which fails the read once the limit is passed
+// (signals.BadRequest turns that into 413 as well).
+func MaxBody(limit int64, overrides ...BodyLimit) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			n := bodyLimit(r.URL.Path, limit, overrides)
+			if n > 0 && r.Body != nil && r.Body != http.NoBody {
+				if r.ContentLength > n {
+					w.Header().Set("Connection", "close")
+					http.Error(w, "Request Entity Too Large - limit is "+strconv.FormatInt(n, 10)+" bytes", http.StatusRequestEntityTooLarge)
+					return
+				}
+				r.Body = http.MaxBytesReader(w, r.Body, n)
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func bodyLimit(path string, limit int64, overrides []BodyLimit) int64 {
+	best := -1
+	for i, o := range overrides {
+		if strings.HasPrefix(path, o.Prefix) && (best < 0 || len(o.Prefix) > len(overrides[best].Prefix)) {
+			best = i
+		}
+	}
+	if best >= 0 {
+		return overrides[best].Limit
+	}
+	return limit
+}
@@ -0,0 +1,79 @@
+package middleware
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ankit-lilly/go-datastar-daisyui-template/internal/signals"
+)
+
+// decodeStep answers 204 if the body decodes as signals and whatever
+// signals.BadRequest says otherwise, as the app's handlers do.
+var decodeStep = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	if _, err := signals.Decode[struct {
+		Step string `json:"step"`
+	}](r); err != nil {
+		signals.BadRequest(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+})
+
+func signalsBody(size int) string {
+	return `{"step":"` + strings.Repeat("x", size) + `"}`
+}
+
+func TestMaxBody(t *testing.T) {
+	handler := MaxBody(64, BodyLimit{Prefix: "/upload", Limit: 0})(decodeStep)
+
+	for _, tc := range []struct {
+		name    string
+		path    string
+		body    string
+		chunked bool
+		want    int
+	}{
+		{"small", "/api", signalsBody(10), false, http.StatusNoContent},
+		{"declared too large", "/api", signalsBody(100), false, http.StatusRequestEntityTooLarge},
+		{"streamed too large", "/api", signalsBody(100), true, http.StatusRequestEntityTooLarge},
+		{"override without limit", "/upload", signalsBody(1000), false, http.StatusNoContent},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var body io.Reader = strings.NewReader(tc.body)
+			if tc.chunked {
+				// Hide the length so only MaxBytesReader can catch it.
+				body = io.MultiReader(body)
+			}
+			req := httptest.NewRequest(http.MethodPost, tc.path, body)
+			req.Header.Set("Content-Type", "application/json")
+			if tc.chunked {
+				req.ContentLength = -1
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+			if rec.Code != tc.want {
+				t.Fatalf("status %d, want %d: %s", rec.Code, tc.want, rec.Body)
+			}
+		})
+	}
+}
+
+func TestMaxBodyRejectsBeforeHandler(t *testing.T) {
+	called := false
+	handler := MaxBody(8)(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+		called = true
+	}))
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader("far too long a body"))
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("status %d, want 413", rec.Code)
+	}
+	if called {
+		t.Fatal("handler ran for a body declared too large")
+	}
+}
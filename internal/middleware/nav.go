@@ -0,0 +1,25 @@
+package middleware
+
+import (
+	"net/http"
+	"slices"
+
+	"github.com/ankit-lilly/go-datastar-daisyui-template/internal/config"
+	"github.com/ankit-lilly/go-datastar-daisyui-template/internal/features"
+	"github.com/ankit-lilly/go-datastar-daisyui-template/internal/views"
+)
+
+// Nav stores the navbar items and the request path in the request context
+// for the layout, which highlights the matching item. items is called per
+// request so the list can change at runtime. Items of disabled features
+// are left out.
+func Nav(items func() []config.NavItem) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			visible := slices.DeleteFunc(slices.Clone(items()), func(item config.NavItem) bool {
+				return item.Feature != "" && !features.Enabled(item.Feature)
+			})
+			next.ServeHTTP(w, r.WithContext(views.WithNav(r.Context(), visible, r.URL.Path)))
+		})
+	}
+}
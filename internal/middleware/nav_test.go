@@ -0,0 +1,36 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	"github.com/ankit-lilly/go-datastar-daisyui-template/internal/config"
+	"github.com/ankit-lilly/go-datastar-daisyui-template/internal/views"
+)
+
+func TestNavHidesDisabledFeatures(t *testing.T) {
+	t.Setenv("FEATURES", "chat=off")
+	cfg, err := config.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	config.Set(cfg)
+	t.Cleanup(func() { config.Set(nil) })
+
+	var labels []string
+	handler := Nav(func() []config.NavItem { return cfg.NavItems })(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for _, item := range views.NavItems(r.Context()) {
+			labels = append(labels, item.Label)
+		}
+	}))
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if slices.Contains(labels, "Chat") {
+		t.Errorf("nav = %v, want no Chat item while chat is off", labels)
+	}
+	if !slices.Contains(labels, "Clock") {
+		t.Errorf("nav = %v, want the Clock item while clock is on", labels)
+	}
+}
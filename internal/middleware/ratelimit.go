@@ -0,0 +1,104 @@
+package middleware
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/ankit-lilly/go-datastar-daisyui-template/internal/store"
+)
+
+// RateLimiter is a per-client-IP token bucket. Put it behind RealIP so
+// clients behind a trusted proxy are told apart.
+type RateLimiter struct {
+	limits  atomic.Pointer[rateLimits]
+	buckets *store.Store[string, *bucket]
+}
+
+type rateLimits struct {
+	rate  float64
+	burst int
+}
+
+// NewRateLimiter allows rate requests per second per IP, with bursts of up
+// to burst requests. A rate of zero lets every request through. Idle
+// buckets expire once they would have refilled. Call Run to reclaim their
+// memory.
+func NewRateLimiter(rate float64, burst int) *RateLimiter {
+	l := &RateLimiter{buckets: store.New[string, *bucket](0)}
+	l.SetLimits(rate, burst)
+	return l
+}
+
+// SetLimits replaces the rate and burst, for example on a config reload.
+// Clients keep the tokens they have, capped at the new burst.
+func (l *RateLimiter) SetLimits(rate float64, burst int) {
+	burst = max(burst, 1)
+	idle := time.Minute
+	if rate > 0 {
+		idle += time.Duration(float64(burst) / rate * float64(time.Second))
+	}
+	l.limits.Store(&rateLimits{rate: rate, burst: burst})
+	l.buckets.SetDefaultTTL(idle)
+}
+
+// Run removes idle buckets every interval until Stop is called.
+func (l *RateLimiter) Run(interval time.Duration) {
+	l.buckets.Run(interval)
+}
+
+func (l *RateLimiter) Stop() {
+	l.buckets.Stop()
+}
+
+// Middleware answers 429 Too Many Requests, with a Retry-After header, to
+// clients that have used up their bucket.
+func (l *RateLimiter) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		limits := l.limits.Load()
+		if limits.rate <= 0 {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		key := r.RemoteAddr
+		if ip, ok := remoteAddr(r); ok {
+			key = ip.String()
+		}
+
+		now := time.Now()
+		b, loaded := l.buckets.LoadOrStore(key, &bucket{tokens: float64(limits.burst), last: now})
+		if loaded {
+			l.buckets.Touch(key)
+		}
+		if wait, ok := b.take(now, limits.rate, float64(limits.burst)); !ok {
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+			http.Error(w, "Too Many Requests", http.StatusTooManyRequests)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+	mu     sync.Mutex
+}
+
+// take refills the bucket for the time since the last call and spends one
+// token. If none is left it reports how long until one is.
+func (b *bucket) take(now time.Time, rate, burst float64) (time.Duration, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.tokens = min(burst, b.tokens+now.Sub(b.last).Seconds()*rate)
+	b.last = now
+	if b.tokens < 1 {
+		return time.Duration((1 - b.tokens) / rate * float64(time.Second)), false
+	}
+	b.tokens--
+	return 0, true
+}
@@ -0,0 +1,39 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func limitedStatus(handler http.Handler) int {
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	return rec.Code
+}
+
+func TestRateLimiterSetLimits(t *testing.T) {
+	limiter := NewRateLimiter(0.001, 1)
+	handler := limiter.Middleware(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+
+	if got := limitedStatus(handler); got != http.StatusOK {
+		t.Fatalf("first request: status %d, want 200", got)
+	}
+	if got := limitedStatus(handler); got != http.StatusTooManyRequests {
+		t.Fatalf("over the burst: status %d, want 429", got)
+	}
+
+	// Turning the limit off lets everything through.
+	limiter.SetLimits(0, 1)
+	for range 5 {
+		if got := limitedStatus(handler); got != http.StatusOK {
+			t.Fatalf("limit off: status %d, want 200", got)
+		}
+	}
+
+	// A faster rate refills the spent bucket straight away.
+	limiter.SetLimits(1e6, 3)
+	if got := limitedStatus(handler); got != http.StatusOK {
+		t.Fatalf("raised limit: status %d, want 200", got)
+	}
+}
@@ -0,0 +1,67 @@
+package middleware
+
+import (
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+// RealIP passes on a copy of the request with RemoteAddr set to the
+// client's address when the request came through one of the trusted
+// proxies; the caller's request is left alone. X-Forwarded-For is read
+// right to left, skipping trusted hops, so a client can't spoof its address
+// by sending the header itself. Without trusted proxies the header is
+// ignored.
+func RealIP(trusted []netip.Prefix) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if len(trusted) == 0 {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if ip, ok := forwardedFor(r, trusted); ok {
+				r = r.Clone(r.Context())
+				r.RemoteAddr = net.JoinHostPort(ip.String(), "0")
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func forwardedFor(r *http.Request, trusted []netip.Prefix) (netip.Addr, bool) {
+	peer, ok := remoteAddr(r)
+	if !ok || !isTrusted(peer, trusted) {
+		return netip.Addr{}, false
+	}
+
+	hops := strings.Split(strings.Join(r.Header.Values("X-Forwarded-For"), ","), ",")
+	for i := len(hops) - 1; i >= 0; i-- {
+		ip, err := netip.ParseAddr(strings.TrimSpace(hops[i]))
+		if err != nil {
+			return netip.Addr{}, false
+		}
+		ip = ip.Unmap()
+		if !isTrusted(ip, trusted) {
+			return ip, true
+		}
+	}
+	return netip.Addr{}, false
+}
+
+// remoteAddr parses the IP of the connection's peer.
+func remoteAddr(r *http.Request) (netip.Addr, bool) {
+	ap, err := netip.ParseAddrPort(r.RemoteAddr)
+	if err != nil {
+		return netip.Addr{}, false
+	}
+	return ap.Addr().Unmap(), true
+}
+
+func isTrusted(ip netip.Addr, trusted []netip.Prefix) bool {
+	for _, p := range trusted {
+		if p.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,48 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+)
+
+func TestRealIP(t *testing.T) {
+	trusted := []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8")}
+
+	for _, tc := range []struct {
+		name    string
+		trusted []netip.Prefix
+		peer    string
+		xff     []string
+		want    string
+	}{
+		{"trusted proxy", trusted, "10.0.0.1:5000", []string{"203.0.113.7"}, "203.0.113.7:0"},
+		{"trusted hops skipped", trusted, "10.0.0.1:5000", []string{"203.0.113.7, 10.0.0.2"}, "203.0.113.7:0"},
+		{"spoofed entries ignored", trusted, "10.0.0.1:5000", []string{"198.51.100.1", "203.0.113.7"}, "203.0.113.7:0"},
+		{"untrusted peer", trusted, "192.0.2.9:5000", []string{"203.0.113.7"}, "192.0.2.9:5000"},
+		{"unparsable hop", trusted, "10.0.0.1:5000", []string{"not-an-ip"}, "10.0.0.1:5000"},
+		{"no trusted proxies", nil, "10.0.0.1:5000", []string{"203.0.113.7"}, "10.0.0.1:5000"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var got string
+			handler := RealIP(tc.trusted)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got = r.RemoteAddr
+			}))
+
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r.RemoteAddr = tc.peer
+			for _, v := range tc.xff {
+				r.Header.Add("X-Forwarded-For", v)
+			}
+			handler.ServeHTTP(httptest.NewRecorder(), r)
+
+			if got != tc.want {
+				t.Fatalf("handler saw RemoteAddr %q, want %q", got, tc.want)
+			}
+			if r.RemoteAddr != tc.peer {
+				t.Fatalf("caller's request changed to %q", r.RemoteAddr)
+			}
+		})
+	}
+}
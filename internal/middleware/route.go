@@ -0,0 +1,40 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"strings"
+)
+
+type routeKey struct{}
+
+// RouteInfo describes how the ServeMux handled a request, for middleware
+// outside it: the ServeMux sets r.Pattern only on its own copy of the
+// request.
+type RouteInfo struct {
+	// Pattern is the matched route pattern, such as "GET /api/jobs", or
+	// empty if the request never reached a handler.
+	Pattern string
+	// Stream reports whether the response was an event stream.
+	Stream bool
+}
+
+// WithRouteInfo returns r with an empty RouteInfo in its context, which
+// MatchRoute fills in once the handler has returned.
+func WithRouteInfo(r *http.Request) (*http.Request, *RouteInfo) {
+	info := new(RouteInfo)
+	return r.WithContext(context.WithValue(r.Context(), routeKey{}, info)), info
+}
+
+// MatchRoute records the matched pattern and whether the response was an
+// event stream in the request's RouteInfo, if it has one. Like SSEMetrics it
+// reads r.Pattern, so it must wrap the ServeMux directly.
+func MatchRoute(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(w, r)
+		if info, ok := r.Context().Value(routeKey{}).(*RouteInfo); ok {
+			info.Pattern = r.Pattern
+			info.Stream = strings.HasPrefix(w.Header().Get("Content-Type"), "text/event-stream")
+		}
+	})
+}
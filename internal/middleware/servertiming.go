@@ -0,0 +1,57 @@
+package middleware
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/ankit-lilly/go-datastar-daisyui-template/internal/servertiming"
+)
+
+// ServerTiming puts a servertiming.Timings in the request context and writes
+// the recorded phases as a Server-Timing header. Headers can't change once
+// the response has started, so the header is written with the status line
+// and total is the time to the first byte; for SSE streams that is when the
+// stream opened, not when it ended.
+func ServerTiming(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx, timings := servertiming.NewContext(r.Context())
+		tw := &timingWriter{ResponseWriter: w, start: time.Now(), timings: timings}
+		next.ServeHTTP(tw, r.WithContext(ctx))
+	})
+}
+
+type timingWriter struct {
+	http.ResponseWriter
+	start       time.Time
+	timings     *servertiming.Timings
+	wroteHeader bool
+}
+
+func (w *timingWriter) WriteHeader(code int) {
+	if !w.wroteHeader {
+		w.wroteHeader = true
+		w.Header().Set(servertiming.HeaderName, w.timings.Header(time.Since(w.start)))
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *timingWriter) Write(b []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// FlushError lets http.ResponseController flush through the wrapper while
+// still writing the header first.
+func (w *timingWriter) FlushError() error {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	return http.NewResponseController(w.ResponseWriter).Flush()
+}
+
+// Unwrap exposes the underlying writer to http.ResponseController.
+func (w *timingWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
@@ -0,0 +1,81 @@
+package middleware
+
+import (
+	"bytes"
+	"expvar"
+	"net/http"
+	"strings"
+)
+
+// sseMetrics is published through expvar as "sse":
+//
+//	element_patches, signal_patches, bytes: totals
+//	*_by_endpoint: the same, keyed by route pattern
+var (
+	sseMetrics                = expvar.NewMap("sse")
+	sseElementPatchesEndpoint = new(expvar.Map).Init()
+	sseSignalPatchesEndpoint  = new(expvar.Map).Init()
+	sseBytesEndpoint          = new(expvar.Map).Init()
+)
+
+func init() {
+	sseMetrics.Set("element_patches_by_endpoint", sseElementPatchesEndpoint)
+	sseMetrics.Set("signal_patches_by_endpoint", sseSignalPatchesEndpoint)
+	sseMetrics.Set("bytes_by_endpoint", sseBytesEndpoint)
+}
+
+var (
+	elementPatchEvent = []byte("event: datastar-patch-elements\n")
+	signalPatchEvent  = []byte("event: datastar-patch-signals\n")
+)
+
+// SSEMetrics counts the Datastar element and signal patches and the bytes
+// written on text/event-stream responses, in total and per route. It reads
+// the route from r.Pattern, so it must wrap the ServeMux directly. datastar
+// writes each event in one Write, which is what makes counting the event
+// lines exact.
+func SSEMetrics(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(&sseMetricsWriter{ResponseWriter: w, r: r}, r)
+	})
+}
+
+type sseMetricsWriter struct {
+	http.ResponseWriter
+	r *http.Request
+}
+
+func (w *sseMetricsWriter) Write(b []byte) (int, error) {
+	n, err := w.ResponseWriter.Write(b)
+	if n > 0 && strings.HasPrefix(w.Header().Get("Content-Type"), "text/event-stream") {
+		w.record(b[:n])
+	}
+	return n, err
+}
+
+func (w *sseMetricsWriter) record(b []byte) {
+	endpoint := w.r.Pattern
+	if endpoint == "" {
+		endpoint = "unmatched"
+	}
+	sseMetrics.Add("bytes", int64(len(b)))
+	sseBytesEndpoint.Add(endpoint, int64(len(b)))
+	if n := bytes.Count(b, elementPatchEvent); n > 0 {
+		sseMetrics.Add("element_patches", int64(n))
+		sseElementPatchesEndpoint.Add(endpoint, int64(n))
+	}
+	if n := bytes.Count(b, signalPatchEvent); n > 0 {
+		sseMetrics.Add("signal_patches", int64(n))
+		sseSignalPatchesEndpoint.Add(endpoint, int64(n))
+	}
+}
+
+// FlushError lets http.ResponseController flush through the wrapper.
+func (w *sseMetricsWriter) FlushError() error {
+	return http.NewResponseController(w.ResponseWriter).Flush()
+}
+
+// Unwrap exposes the underlying writer to http.ResponseController.
+func (w *sseMetricsWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
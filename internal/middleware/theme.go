@@ -0,0 +1,32 @@
+package middleware
+
+import (
+	"net/http"
+	"slices"
+
+	"github.com/ankit-lilly/go-datastar-daisyui-template/internal/views"
+)
+
+const ThemeCookieName = "theme"
+
+// Theme reads the theme cookie and stores the selected theme, along with
+// the available themes, in the request context for the layout. Unknown
+// cookie values fall back to the first available theme. themes is called
+// per request so the list can change at runtime.
+func Theme(themes func() []string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			themes := themes()
+			current := ""
+			if len(themes) > 0 {
+				current = themes[0]
+			}
+			if c, err := r.Cookie(ThemeCookieName); err == nil {
+				if c.Value == views.ThemeSystem || slices.Contains(themes, c.Value) {
+					current = c.Value
+				}
+			}
+			next.ServeHTTP(w, r.WithContext(views.WithTheme(r.Context(), current, themes)))
+		})
+	}
+}
@@ -0,0 +1,121 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Timeout gives each request a context deadline d away and answers 503 if
+// the handler hasn't started its response by then, like
+// http.TimeoutHandler. Unlike it, writes aren't buffered: they go straight
+// to the client and flush, so handlers answering with a short SSE response
+// still work. Once a response has started the deadline only cancels the
+// handler's context and later writes fail with http.ErrHandlerTimeout.
+//
+// Don't put long-lived SSE streams behind it; they would be cut off after d.
+// A d of zero or less disables the deadline.
+func Timeout(d time.Duration) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if d <= 0 {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx, cancel := context.WithTimeout(r.Context(), d)
+			defer cancel()
+
+			tw := &timeoutWriter{w: w, header: w.Header().Clone()}
+			done := make(chan struct{})
+			panicked := make(chan any, 1)
+			go func() {
+				defer func() {
+					if p := recover(); p != nil {
+						panicked <- p
+					}
+				}()
+				next.ServeHTTP(tw, r.WithContext(ctx))
+				close(done)
+			}()
+
+			select {
+			case p := <-panicked:
+				panic(p)
+			case <-done:
+			case <-ctx.Done():
+				tw.mu.Lock()
+				defer tw.mu.Unlock()
+				tw.timedOut = true
+				if !tw.wroteHeader && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+					http.Error(w, "Service Unavailable: request timed out", http.StatusServiceUnavailable)
+				}
+			}
+		})
+	}
+}
+
+// timeoutWriter hands the handler its own header map, since the 503 may be
+// written to the real one from another goroutine, and guards writes so
+// none reach the client after Timeout has returned.
+type timeoutWriter struct {
+	w      http.ResponseWriter
+	header http.Header
+
+	mu          sync.Mutex
+	wroteHeader bool
+	timedOut    bool
+}
+
+func (tw *timeoutWriter) Header() http.Header {
+	return tw.header
+}
+
+func (tw *timeoutWriter) WriteHeader(code int) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if tw.timedOut || tw.wroteHeader {
+		return
+	}
+	tw.writeHeader(code)
+}
+
+func (tw *timeoutWriter) writeHeader(code int) {
+	tw.wroteHeader = true
+	dst := tw.w.Header()
+	for k, v := range tw.header {
+		dst[k] = v
+	}
+	tw.w.WriteHeader(code)
+}
+
+func (tw *timeoutWriter) Write(b []byte) (int, error) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if tw.timedOut {
+		return 0, http.ErrHandlerTimeout
+	}
+	if !tw.wroteHeader {
+		tw.writeHeader(http.StatusOK)
+	}
+	return tw.w.Write(b)
+}
+
+// FlushError lets http.ResponseController flush through the wrapper.
+func (tw *timeoutWriter) FlushError() error {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if tw.timedOut {
+		return http.ErrHandlerTimeout
+	}
+	if !tw.wroteHeader {
+		tw.writeHeader(http.StatusOK)
+	}
+	return http.NewResponseController(tw.w).Flush()
+}
+
+// Unwrap lets http.ResponseController reach the connection for calls the
+// wrapper doesn't handle itself, such as SetWriteDeadline.
+func (tw *timeoutWriter) Unwrap() http.ResponseWriter {
+	return tw.w
+}
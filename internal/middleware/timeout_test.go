@@ -0,0 +1,33 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+type deadlineRecorder struct {
+	*httptest.ResponseRecorder
+	deadline time.Time
+}
+
+func (w *deadlineRecorder) SetWriteDeadline(t time.Time) error {
+	w.deadline = t
+	return nil
+}
+
+func TestTimeoutPassesWriteDeadlineThrough(t *testing.T) {
+	want := time.Now().Add(time.Minute)
+	handler := Timeout(time.Second)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := http.NewResponseController(w).SetWriteDeadline(want); err != nil {
+			t.Errorf("SetWriteDeadline: %v", err)
+		}
+	}))
+
+	w := &deadlineRecorder{ResponseRecorder: httptest.NewRecorder()}
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	if !w.deadline.Equal(want) {
+		t.Fatalf("deadline %v, want %v", w.deadline, want)
+	}
+}
@@ -0,0 +1,14 @@
+package middleware
+
+import (
+	"net/http"
+
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+)
+
+// Tracing starts a server span for every request, continuing any trace
+// context sent in the request headers. Spans are dropped unless a tracer
+// provider was installed (see the telemetry package).
+func Tracing(next http.Handler) http.Handler {
+	return otelhttp.NewHandler(next, "http.server")
+}
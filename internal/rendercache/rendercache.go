@@ -0,0 +1,65 @@
+// Package rendercache caches the rendered bytes of templ components that
+// rarely change, so hot pages aren't re-rendered on every request.
+package rendercache
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"time"
+
+	"github.com/a-h/templ"
+	"github.com/ankit-lilly/go-datastar-daisyui-template/internal/store"
+)
+
+// Cache holds rendered output by key for a fixed TTL.
+//
+// Only cache components whose output is the same for every request that
+// maps to a key: anything read from the context (CSRF tokens, the current
+// theme, the theme list) must either stay outside the cached component or
+// be part of the key.
+type Cache struct {
+	ttl     time.Duration
+	entries *store.Store[string, []byte]
+}
+
+// New returns a cache whose entries expire after ttl. A ttl of zero or less
+// disables caching: Component renders every time.
+func New(ttl time.Duration) *Cache {
+	return &Cache{ttl: ttl, entries: store.New[string, []byte](ttl)}
+}
+
+// Component returns a component that writes the output cached under key,
+// rendering c and caching the result on a miss. Failed renders are not
+// cached.
+func (c *Cache) Component(key string, comp templ.Component) templ.Component {
+	if c.ttl <= 0 {
+		return comp
+	}
+	return templ.ComponentFunc(func(ctx context.Context, w io.Writer) error {
+		if b, ok := c.entries.Get(key); ok {
+			_, err := w.Write(b)
+			return err
+		}
+		var buf bytes.Buffer
+		if err := comp.Render(ctx, &buf); err != nil {
+			return err
+		}
+		c.entries.Set(key, buf.Bytes())
+		_, err := w.Write(buf.Bytes())
+		return err
+	})
+}
+
+// Invalidate drops the output cached under key.
+func (c *Cache) Invalidate(key string) {
+	c.entries.Delete(key)
+}
+
+// Clear drops every cached output.
+func (c *Cache) Clear() {
+	c.entries.Range(func(key string, _ []byte) bool {
+		c.entries.Delete(key)
+		return true
+	})
+}
@@ -0,0 +1,80 @@
+package rendercache_test
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/a-h/templ"
+	"github.com/ankit-lilly/go-datastar-daisyui-template/internal/rendercache"
+	"github.com/ankit-lilly/go-datastar-daisyui-template/internal/views"
+)
+
+func render(t testing.TB, c templ.Component) string {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := c.Render(context.Background(), &buf); err != nil {
+		t.Fatalf("Render: %v", err)
+	}
+	return buf.String()
+}
+
+// counting renders its count of renders so far.
+func counting(n *int) templ.Component {
+	return templ.ComponentFunc(func(_ context.Context, w io.Writer) error {
+		*n++
+		_, err := io.WriteString(w, "render "+strconv.Itoa(*n))
+		return err
+	})
+}
+
+func TestCacheHitsAndInvalidate(t *testing.T) {
+	cache := rendercache.New(time.Hour)
+	var n int
+	comp := cache.Component("k", counting(&n))
+
+	if a, b := render(t, comp), render(t, comp); a != "render 1" || b != a {
+		t.Fatalf("renders = %q, %q; want the first output twice", a, b)
+	}
+	cache.Invalidate("k")
+	if got := render(t, comp); got != "render 2" {
+		t.Fatalf("after Invalidate = %q, want a fresh render", got)
+	}
+}
+
+func TestCacheDisabled(t *testing.T) {
+	cache := rendercache.New(0)
+	var n int
+	comp := cache.Component("k", counting(&n))
+	render(t, comp)
+	render(t, comp)
+	if n != 2 {
+		t.Fatalf("rendered %d times, want 2 with caching off", n)
+	}
+}
+
+// BenchmarkIndexContent compares rendering the home page body every time
+// with serving it from the cache, as the index handler does.
+func BenchmarkIndexContent(b *testing.B) {
+	for _, bc := range []struct {
+		name string
+		ttl  time.Duration
+	}{
+		{"uncached", 0},
+		{"cached", time.Hour},
+	} {
+		b.Run(bc.name, func(b *testing.B) {
+			comp := rendercache.New(bc.ttl).Component("index", views.IndexContent(false))
+			ctx := context.Background()
+			b.ReportAllocs()
+			for b.Loop() {
+				if err := comp.Render(ctx, io.Discard); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,87 @@
+// Package servertiming collects per-request phase durations for the
+// Server-Timing response header, which browser dev tools show in the
+// network panel.
+package servertiming
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// HeaderName is the response header the timings are written to.
+const HeaderName = "Server-Timing"
+
+type metric struct {
+	name string
+	dur  time.Duration
+}
+
+// Timings holds the phases recorded for one request. It is safe for
+// concurrent use.
+type Timings struct {
+	metrics []metric
+	mu      sync.Mutex
+}
+
+type contextKey struct{}
+
+// NewContext returns a copy of ctx carrying a fresh Timings.
+func NewContext(ctx context.Context) (context.Context, *Timings) {
+	t := &Timings{}
+	return context.WithValue(ctx, contextKey{}, t), t
+}
+
+// FromContext returns the Timings in ctx, or nil.
+func FromContext(ctx context.Context) *Timings {
+	t, _ := ctx.Value(contextKey{}).(*Timings)
+	return t
+}
+
+// Record adds dur to the phase name for the request in ctx. Recording the
+// same name twice sums the durations. It is a no-op when ctx carries no
+// Timings, so handlers can record unconditionally.
+func Record(ctx context.Context, name string, dur time.Duration) {
+	if t := FromContext(ctx); t != nil {
+		t.Add(name, dur)
+	}
+}
+
+// Since records the time elapsed since start under name. It is meant to be
+// deferred: defer servertiming.Since(ctx, "db", time.Now()).
+func Since(ctx context.Context, name string, start time.Time) {
+	Record(ctx, name, time.Since(start))
+}
+
+func (t *Timings) Add(name string, dur time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for i := range t.metrics {
+		if t.metrics[i].name == name {
+			t.metrics[i].dur += dur
+			return
+		}
+	}
+	t.metrics = append(t.metrics, metric{name: name, dur: dur})
+}
+
+// Header formats the recorded phases in the order they were first recorded,
+// followed by total, e.g. "render;dur=1.204, total;dur=1.530".
+func (t *Timings) Header(total time.Duration) string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	parts := make([]string, 0, len(t.metrics)+1)
+	for _, m := range t.metrics {
+		parts = append(parts, format(m.name, m.dur))
+	}
+	parts = append(parts, format("total", total))
+	return strings.Join(parts, ", ")
+}
+
+// format renders one metric with its duration in milliseconds, the unit the
+// header uses.
+func format(name string, dur time.Duration) string {
+	return fmt.Sprintf("%s;dur=%.3f", name, float64(dur)/float64(time.Millisecond))
+}
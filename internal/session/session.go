@@ -0,0 +1,235 @@
+package session
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ankit-lilly/go-datastar-daisyui-template/internal/util"
+)
+
+const CookieName = "session"
+
+var ErrInvalidCookie = errors.New("session: invalid cookie")
+
+type Session struct {
+	ID        string
+	CreatedAt time.Time
+	ExpiresAt time.Time
+
+	values map[string]any
+	mu     sync.RWMutex
+}
+
+func (s *Session) Get(key string) (any, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	v, ok := s.values[key]
+	return v, ok
+}
+
+func (s *Session) Set(key string, value any) {
+	s.mu.Lock()
+	s.values[key] = value
+	s.mu.Unlock()
+}
+
+func (s *Session) Delete(key string) {
+	s.mu.Lock()
+	delete(s.values, key)
+	s.mu.Unlock()
+}
+
+// Manager issues HMAC-signed session cookies and keeps session data in
+// memory. Sessions slide forward on every request and expire after ttl of
+// inactivity.
+type Manager struct {
+	secret   []byte
+	ttl      time.Duration
+	sessions map[string]*Session
+	done     chan struct{}
+	mu       sync.RWMutex
+}
+
+func NewManager(secret []byte, ttl time.Duration) *Manager {
+	return &Manager{
+		secret:   secret,
+		ttl:      ttl,
+		sessions: make(map[string]*Session),
+		done:     make(chan struct{}),
+	}
+}
+
+// Run periodically removes expired sessions until Stop is called.
+func (m *Manager) Run() {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			m.removeExpired(time.Now())
+		case <-m.done:
+			return
+		}
+	}
+}
+
+func (m *Manager) Stop() {
+	close(m.done)
+}
+
+// Middleware loads the session for the request (creating one if needed),
+// refreshes its cookie and stores it in the request context.
+func (m *Manager) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s, err := m.load(r)
+		if err != nil {
+			s = m.create()
+		}
+		m.Save(w, s)
+		next.ServeHTTP(w, r.WithContext(withSession(r.Context(), s)))
+	})
+}
+
+// Get returns the session for the request, preferring the one loaded by
+// Middleware. It returns nil if the request has no valid session.
+func (m *Manager) Get(r *http.Request) *Session {
+	if s := FromContext(r.Context()); s != nil {
+		return s
+	}
+	s, err := m.load(r)
+	if err != nil {
+		return nil
+	}
+	return s
+}
+
+// Save extends the session's expiry and writes its signed cookie.
+func (m *Manager) Save(w http.ResponseWriter, s *Session) {
+	m.mu.Lock()
+	s.ExpiresAt = time.Now().Add(m.ttl)
+	m.sessions[s.ID] = s
+	m.mu.Unlock()
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     CookieName,
+		Value:    m.encode(s.ID),
+		Path:     "/",
+		Expires:  s.ExpiresAt,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// Rotate moves the session's data to a fresh ID, invalidating the old one.
+// Call it on privilege changes such as login.
+func (m *Manager) Rotate(w http.ResponseWriter, s *Session) *Session {
+	next := m.create()
+	s.mu.RLock()
+	for k, v := range s.values {
+		next.values[k] = v
+	}
+	s.mu.RUnlock()
+
+	m.mu.Lock()
+	delete(m.sessions, s.ID)
+	m.mu.Unlock()
+
+	m.Save(w, next)
+	return next
+}
+
+// Destroy removes the session and clears its cookie.
+func (m *Manager) Destroy(w http.ResponseWriter, s *Session) {
+	m.mu.Lock()
+	delete(m.sessions, s.ID)
+	m.mu.Unlock()
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     CookieName,
+		Value:    "",
+		Path:     "/",
+		MaxAge:   -1,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+func (m *Manager) create() *Session {
+	now := time.Now()
+	return &Session{
+		ID:        util.GenerateID(),
+		CreatedAt: now,
+		ExpiresAt: now.Add(m.ttl),
+		values:    make(map[string]any),
+	}
+}
+
+func (m *Manager) load(r *http.Request) (*Session, error) {
+	c, err := r.Cookie(CookieName)
+	if err != nil {
+		return nil, err
+	}
+	id, err := m.decode(c.Value)
+	if err != nil {
+		return nil, err
+	}
+
+	m.mu.RLock()
+	s, ok := m.sessions[id]
+	m.mu.RUnlock()
+	if !ok || time.Now().After(s.ExpiresAt) {
+		return nil, ErrInvalidCookie
+	}
+	return s, nil
+}
+
+func (m *Manager) removeExpired(now time.Time) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for id, s := range m.sessions {
+		if now.After(s.ExpiresAt) {
+			delete(m.sessions, id)
+		}
+	}
+}
+
+func (m *Manager) encode(id string) string {
+	return id + "." + base64.RawURLEncoding.EncodeToString(m.sign(id))
+}
+
+func (m *Manager) decode(value string) (string, error) {
+	id, sig, ok := strings.Cut(value, ".")
+	if !ok {
+		return "", ErrInvalidCookie
+	}
+	got, err := base64.RawURLEncoding.DecodeString(sig)
+	if err != nil || !hmac.Equal(got, m.sign(id)) {
+		return "", ErrInvalidCookie
+	}
+	return id, nil
+}
+
+func (m *Manager) sign(id string) []byte {
+	mac := hmac.New(sha256.New, m.secret)
+	mac.Write([]byte(id))
+	return mac.Sum(nil)
+}
+
+type contextKey struct{}
+
+func withSession(ctx context.Context, s *Session) context.Context {
+	return context.WithValue(ctx, contextKey{}, s)
+}
+
+// FromContext returns the session stored by Middleware, or nil.
+func FromContext(ctx context.Context) *Session {
+	s, _ := ctx.Value(contextKey{}).(*Session)
+	return s
+}
@@ -0,0 +1,143 @@
+package session
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+var testSecret = []byte("test-secret")
+
+// requestWith returns a request carrying the session cookie set on rec.
+func requestWith(t *testing.T, rec *httptest.ResponseRecorder) *http.Request {
+	t.Helper()
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	for _, c := range rec.Result().Cookies() {
+		if c.Name == CookieName {
+			r.AddCookie(c)
+			return r
+		}
+	}
+	t.Fatal("no session cookie set")
+	return nil
+}
+
+func TestEncodeDecodeRoundTrip(t *testing.T) {
+	m := NewManager(testSecret, time.Hour)
+	id, err := m.decode(m.encode("abc123"))
+	if err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if id != "abc123" {
+		t.Fatalf("id = %q, want abc123", id)
+	}
+}
+
+func TestDecodeRejectsTampering(t *testing.T) {
+	m := NewManager(testSecret, time.Hour)
+	valid := m.encode("abc123")
+	id, sig, _ := strings.Cut(valid, ".")
+	flipped := []byte(sig)
+	flipped[0] ^= 1
+
+	for name, value := range map[string]string{
+		"no signature":    id,
+		"empty signature": id + ".",
+		"changed id":      "abc124." + sig,
+		"changed sig":     id + "." + string(flipped),
+		"not base64":      id + ".!!!",
+		"other secret":    NewManager([]byte("other"), time.Hour).encode(id),
+	} {
+		if _, err := m.decode(value); !errors.Is(err, ErrInvalidCookie) {
+			t.Errorf("%s: err = %v, want ErrInvalidCookie", name, err)
+		}
+	}
+}
+
+func TestMiddlewareKeepsSession(t *testing.T) {
+	m := NewManager(testSecret, time.Hour)
+	var seen []*Session
+	handler := m.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = append(seen, FromContext(r.Context()))
+	}))
+
+	first := httptest.NewRecorder()
+	handler.ServeHTTP(first, httptest.NewRequest(http.MethodGet, "/", nil))
+	seen[0].Set("k", "v")
+
+	handler.ServeHTTP(httptest.NewRecorder(), requestWith(t, first))
+	if seen[1] != seen[0] {
+		t.Fatal("second request got a different session")
+	}
+	if v, _ := seen[1].Get("k"); v != "v" {
+		t.Fatalf("value = %v, want v", v)
+	}
+}
+
+func TestTamperedCookieGetsNewSession(t *testing.T) {
+	m := NewManager(testSecret, time.Hour)
+	rec := httptest.NewRecorder()
+	m.Save(rec, m.create())
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	cookie := rec.Result().Cookies()[0]
+	cookie.Value = strings.Replace(cookie.Value, ".", "x.", 1)
+	r.AddCookie(cookie)
+	if s := m.Get(r); s != nil {
+		t.Fatalf("tampered cookie loaded session %s", s.ID)
+	}
+}
+
+func TestExpiry(t *testing.T) {
+	m := NewManager(testSecret, time.Hour)
+	s := m.create()
+	rec := httptest.NewRecorder()
+	m.Save(rec, s)
+	r := requestWith(t, rec)
+
+	if got := m.Get(r); got != s {
+		t.Fatal("fresh session not loaded")
+	}
+
+	m.mu.Lock()
+	s.ExpiresAt = time.Now().Add(-time.Second)
+	m.mu.Unlock()
+	if got := m.Get(r); got != nil {
+		t.Fatal("expired session still loaded")
+	}
+
+	m.removeExpired(time.Now())
+	m.mu.RLock()
+	_, ok := m.sessions[s.ID]
+	m.mu.RUnlock()
+	if ok {
+		t.Fatal("removeExpired kept an expired session")
+	}
+}
+
+func TestRotate(t *testing.T) {
+	m := NewManager(testSecret, time.Hour)
+	old := m.create()
+	old.Set("user", "alice")
+	oldRec := httptest.NewRecorder()
+	m.Save(oldRec, old)
+
+	newRec := httptest.NewRecorder()
+	next := m.Rotate(newRec, old)
+
+	if next.ID == old.ID {
+		t.Fatal("Rotate kept the ID")
+	}
+	if v, _ := next.Get("user"); v != "alice" {
+		t.Fatalf("value = %v, want alice", v)
+	}
+	if got := m.Get(requestWith(t, oldRec)); got != nil {
+		t.Fatal("old cookie still valid after Rotate")
+	}
+	if got := m.Get(requestWith(t, newRec)); got != next {
+		t.Fatal("new cookie does not load the rotated session")
+	}
+}
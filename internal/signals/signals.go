@@ -0,0 +1,125 @@
+// Package signals decodes Datastar signals into typed structs and validates
+// them with go-playground/validator struct tags.
+package signals
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/go-playground/validator/v10/non-standard/validators"
+	"github.com/starfederation/datastar-go/datastar"
+)
+
+// ErrInvalid is wrapped by every error Decode returns, so handlers can
+// answer 400 for anything coming back from it.
+var ErrInvalid = errors.New("invalid signals")
+
+// ErrTooLarge is returned when the body exceeds the middleware.MaxBody
+// limit. It wraps ErrInvalid.
+var ErrTooLarge = fmt.Errorf("%w: payload too large", ErrInvalid)
+
+var validate = newValidator()
+
+func newValidator() *validator.Validate {
+	v := validator.New(validator.WithRequiredStructEnabled())
+	// Report fields by their signal name rather than the Go field name.
+	v.RegisterTagNameFunc(func(f reflect.StructField) string {
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "" || name == "-" {
+			return f.Name
+		}
+		return name
+	})
+	if err := v.RegisterValidation("notblank", validators.NotBlank); err != nil {
+		panic(err)
+	}
+	return v
+}
+
+// Decode reads the request's signals into a T and validates its `validate`
+// tags. On failure the returned error wraps ErrInvalid and its message is
+// safe to show to the client.
+//
+//	var req struct {
+//		Text string `json:"text" validate:"notblank,max=500"`
+//	}
+func Decode[T any](r *http.Request) (T, error) {
+	var v T
+	if err := read(r, &v); err != nil {
+		return v, err
+	}
+	if err := validate.Struct(v); err != nil {
+		var verrs validator.ValidationErrors
+		if !errors.As(err, &verrs) {
+			return v, fmt.Errorf("%w: %v", ErrInvalid, err)
+		}
+		msgs := make([]string, len(verrs))
+		for i, fe := range verrs {
+			msgs[i] = describe(fe)
+		}
+		return v, fmt.Errorf("%w: %s", ErrInvalid, strings.Join(msgs, "; "))
+	}
+	return v, nil
+}
+
+// DecodeAny reads whatever signals the request carries, without
+// validation. Errors are as for Decode.
+func DecodeAny(r *http.Request) (map[string]any, error) {
+	v := map[string]any{}
+	if err := read(r, &v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+func read(r *http.Request, v any) error {
+	if err := datastar.ReadSignals(r, v); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			return fmt.Errorf("%w (limit %d bytes)", ErrTooLarge, tooLarge.Limit)
+		}
+		return fmt.Errorf("%w: malformed payload", ErrInvalid)
+	}
+	return nil
+}
+
+// BadRequest answers a Decode error with 400 and its message, or 413 for
+// ErrTooLarge.
+func BadRequest(w http.ResponseWriter, err error) {
+	if errors.Is(err, ErrTooLarge) {
+		http.Error(w, "Request Entity Too Large: "+err.Error(), http.StatusRequestEntityTooLarge)
+		return
+	}
+	http.Error(w, "Bad Request: "+err.Error(), http.StatusBadRequest)
+}
+
+// Message returns err's text without the ErrInvalid prefix, for showing
+// validation failures to users.
+func Message(err error) string {
+	return strings.TrimPrefix(err.Error(), ErrInvalid.Error()+": ")
+}
+
+func describe(fe validator.FieldError) string {
+	unit := ""
+	if fe.Kind() == reflect.String {
+		unit = " characters"
+	}
+	switch fe.Tag() {
+	case "required", "notblank":
+		return fe.Field() + " is required"
+	case "max":
+		return fmt.Sprintf("%s must be at most %s%s", fe.Field(), fe.Param(), unit)
+	case "min":
+		return fmt.Sprintf("%s must be at least %s%s", fe.Field(), fe.Param(), unit)
+	case "email":
+		return fe.Field() + " must be a valid email address"
+	case "oneof":
+		return fmt.Sprintf("%s must be one of %s", fe.Field(), fe.Param())
+	default:
+		return fmt.Sprintf("%s failed %s validation", fe.Field(), fe.Tag())
+	}
+}
@@ -0,0 +1,78 @@
+package signals
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type message struct {
+	Text  string `json:"text" validate:"notblank,max=10"`
+	Count int    `json:"count" validate:"min=1"`
+}
+
+func post(body string) *http.Request {
+	r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+	r.Header.Set("Datastar-Request", "true")
+	return r
+}
+
+func TestDecode(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		wantErr string
+	}{
+		{"valid", `{"text":"hi","count":2}`, ""},
+		{"extra signals ignored", `{"text":"hi","count":2,"other":true}`, ""},
+		{"missing field", `{"count":2}`, "text is required"},
+		{"blank field", `{"text":"   ","count":2}`, "text is required"},
+		{"all missing", `{}`, "text is required; count must be at least 1"},
+		{"too long", `{"text":"far too long","count":2}`, "text must be at most 10 characters"},
+		{"type mismatch", `{"text":"hi","count":"two"}`, "malformed payload"},
+		{"not JSON", `text=hi`, "malformed payload"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Decode[message](post(tt.body))
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("Decode: %v", err)
+				}
+				if got.Text != "hi" || got.Count != 2 {
+					t.Fatalf("Decode = %+v, want hi and 2", got)
+				}
+				return
+			}
+			if !errors.Is(err, ErrInvalid) {
+				t.Fatalf("Decode error = %v, want one wrapping ErrInvalid", err)
+			}
+			if msg := Message(err); msg != tt.wantErr {
+				t.Fatalf("Message = %q, want %q", msg, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestBadRequest(t *testing.T) {
+	rec := httptest.NewRecorder()
+	_, err := Decode[message](post(`{}`))
+	BadRequest(rec, err)
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("status %d, want 400", rec.Code)
+	}
+
+	r := post(`{"text":"hi","count":2}`)
+	r.Body = http.MaxBytesReader(httptest.NewRecorder(), r.Body, 4)
+	_, err = Decode[message](r)
+	if !errors.Is(err, ErrTooLarge) {
+		t.Fatalf("Decode of an oversized body = %v, want ErrTooLarge", err)
+	}
+	rec = httptest.NewRecorder()
+	BadRequest(rec, err)
+	if rec.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("status %d, want 413", rec.Code)
+	}
+}
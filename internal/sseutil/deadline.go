@@ -0,0 +1,62 @@
+package sseutil
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/starfederation/datastar-go/datastar"
+)
+
+// NewSSE opens an event stream like datastar.NewSSE, but every event must
+// reach the client within writeTimeout. The server has no WriteTimeout so
+// streams can stay open, which would otherwise let a stuck client hold its
+// connection and handler forever. When a write times out or fails, the
+// stream's context is cancelled, so IsClosed reports true and handlers
+// waiting on sse.Context().Done() return. Zero disables the deadline.
+func NewSSE(w http.ResponseWriter, r *http.Request, writeTimeout time.Duration) *datastar.ServerSentEventGenerator {
+	if writeTimeout <= 0 {
+		return datastar.NewSSE(w, r)
+	}
+	ctx, cancel := context.WithCancel(r.Context())
+	dw := &deadlineWriter{
+		ResponseWriter: w,
+		rc:             http.NewResponseController(w),
+		timeout:        writeTimeout,
+		cancel:         cancel,
+	}
+	return datastar.NewSSE(dw, r.WithContext(ctx))
+}
+
+// deadlineWriter sets a write deadline before each event and clears it once
+// the event is flushed, so an idle stream never trips it.
+type deadlineWriter struct {
+	http.ResponseWriter
+	rc      *http.ResponseController
+	timeout time.Duration
+	cancel  context.CancelFunc
+}
+
+func (w *deadlineWriter) Write(p []byte) (int, error) {
+	// Writers that cannot take deadlines, such as test recorders, just
+	// write without one.
+	w.rc.SetWriteDeadline(time.Now().Add(w.timeout))
+	n, err := w.ResponseWriter.Write(p)
+	if err != nil {
+		w.cancel()
+	}
+	return n, err
+}
+
+func (w *deadlineWriter) FlushError() error {
+	if err := w.rc.Flush(); err != nil {
+		w.cancel()
+		return err
+	}
+	w.rc.SetWriteDeadline(time.Time{})
+	return nil
+}
+
+func (w *deadlineWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
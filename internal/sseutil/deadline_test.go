@@ -0,0 +1,81 @@
+package sseutil
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// deadlineRecorder records the write deadlines set through
+// http.ResponseController.
+type deadlineRecorder struct {
+	*httptest.ResponseRecorder
+	deadlines []time.Time
+}
+
+func (w *deadlineRecorder) SetWriteDeadline(t time.Time) error {
+	w.deadlines = append(w.deadlines, t)
+	return nil
+}
+
+func TestStreamsStartSetsWriteDeadline(t *testing.T) {
+	streams := newTestStreams(0)
+	streams.WriteTimeout = time.Second
+
+	w := &deadlineRecorder{ResponseRecorder: httptest.NewRecorder()}
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("Datastar-Request", "true")
+	sse, ok := streams.Start(w, r)
+	if !ok {
+		t.Fatalf("Start rejected the request: %d", w.Code)
+	}
+	if err := sse.PatchElements(`<div id="x"></div>`); err != nil {
+		t.Fatal(err)
+	}
+
+	// Each event sets a deadline and clears it once flushed.
+	var set, cleared bool
+	for _, d := range w.deadlines {
+		if d.IsZero() {
+			cleared = set
+		} else {
+			set = true
+		}
+	}
+	if !set || !cleared {
+		t.Fatalf("deadlines %v, want one set and then cleared", w.deadlines)
+	}
+}
+
+func TestNewSSEDropsBlockedClient(t *testing.T) {
+	closed := make(chan bool, 1)
+	event := []byte(`{"pad":"` + strings.Repeat("x", 64<<10) + `"}`)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sse := NewSSE(w, r, 100*time.Millisecond)
+		for sse.PatchSignals(event) == nil {
+		}
+		closed <- sse.IsClosed()
+	}))
+	defer srv.Close()
+
+	// A client that sends its request and then never reads.
+	conn, err := net.Dial("tcp", srv.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	fmt.Fprint(conn, "GET / HTTP/1.1\r\nHost: test\r\nAccept: text/event-stream\r\n\r\n")
+
+	select {
+	case isClosed := <-closed:
+		if !isClosed {
+			t.Fatal("write failed but the stream's context is still live")
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("handler still writing to a client that stopped reading")
+	}
+}
@@ -0,0 +1,38 @@
+package sseutil
+
+// Limiter caps how many long-lived streams are open at once, so a
+// connection storm is turned away before it exhausts goroutines and file
+// descriptors.
+type Limiter struct {
+	slots chan struct{}
+}
+
+// NewLimiter returns a limiter allowing n concurrent streams. Zero or less
+// means no limit.
+func NewLimiter(n int) *Limiter {
+	if n <= 0 {
+		return &Limiter{}
+	}
+	return &Limiter{slots: make(chan struct{}, n)}
+}
+
+// Acquire takes a slot without blocking and reports whether one was free.
+// Every successful Acquire must be paired with a Release.
+func (l *Limiter) Acquire() bool {
+	if l.slots == nil {
+		return true
+	}
+	select {
+	case l.slots <- struct{}{}:
+		return true
+	default:
+		return false
+	}
+}
+
+// Release frees a slot taken by Acquire.
+func (l *Limiter) Release() {
+	if l.slots != nil {
+		<-l.slots
+	}
+}
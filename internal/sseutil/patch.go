@@ -0,0 +1,66 @@
+package sseutil
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/a-h/templ"
+	"github.com/starfederation/datastar-go/datastar"
+)
+
+// PatchOption configures Patch.
+type PatchOption func(*patchConfig)
+
+type patchConfig struct {
+	mode   datastar.ElementPatchMode
+	target string
+}
+
+// WithMode sets how the patch is merged into the page, e.g.
+// datastar.ElementPatchModeAppend for chat or log lines. The default is
+// datastar.DefaultElementPatchMode, which morphs the element with the same
+// ID.
+func WithMode(mode datastar.ElementPatchMode) PatchOption {
+	return func(c *patchConfig) {
+		c.mode = mode
+	}
+}
+
+// WithTarget patches relative to the element with id rather than the
+// component's own root ID. Modes other than outer and replace usually need
+// one.
+func WithTarget(id string) PatchOption {
+	return func(c *patchConfig) {
+		c.target = id
+	}
+}
+
+// Patch renders c with ctx and sends it as a patch-elements event. Rendering
+// finishes before anything is written, so a failing component never sends
+// a partial fragment, and an unknown mode is an error rather than something
+// the browser silently ignores.
+//
+//	sseutil.Patch(sse, r.Context(), views.JobLogLine(line),
+//		sseutil.WithTarget(views.JobLogID), sseutil.WithMode(datastar.ElementPatchModeAppend))
+func Patch(sse *datastar.ServerSentEventGenerator, ctx context.Context, c templ.Component, opts ...PatchOption) error {
+	cfg := patchConfig{mode: datastar.DefaultElementPatchMode}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	if !slices.Contains(datastar.ValidElementPatchModes, cfg.mode) {
+		return fmt.Errorf("sseutil: invalid patch mode %q", cfg.mode)
+	}
+
+	var buf bytes.Buffer
+	if err := c.Render(ctx, &buf); err != nil {
+		return fmt.Errorf("sseutil: render: %w", err)
+	}
+
+	patchOpts := []datastar.PatchElementOption{datastar.WithMode(cfg.mode)}
+	if cfg.target != "" {
+		patchOpts = append(patchOpts, datastar.WithSelectorID(cfg.target))
+	}
+	return sse.PatchElements(buf.String(), patchOpts...)
+}
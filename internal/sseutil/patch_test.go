@@ -0,0 +1,82 @@
+package sseutil
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/a-h/templ"
+	"github.com/starfederation/datastar-go/datastar"
+)
+
+func TestPatchModes(t *testing.T) {
+	line := templ.ComponentFunc(func(_ context.Context, w io.Writer) error {
+		_, err := io.WriteString(w, `<li id="line">hi</li>`)
+		return err
+	})
+
+	tests := []struct {
+		name   string
+		opts   []PatchOption
+		want   []string
+		absent []string
+	}{
+		{
+			name:   "default",
+			want:   []string{"event: datastar-patch-elements\n", `data: elements <li id="line">hi</li>` + "\n"},
+			absent: []string{"data: mode", "data: selector"},
+		},
+		{
+			name: "append to target",
+			opts: []PatchOption{WithMode(datastar.ElementPatchModeAppend), WithTarget("log")},
+			want: []string{"data: mode append\n", "data: selector #log\n", `data: elements <li id="line">hi</li>` + "\n"},
+		},
+		{
+			name:   "inner",
+			opts:   []PatchOption{WithMode(datastar.ElementPatchModeInner), WithTarget("log")},
+			want:   []string{"data: mode inner\n", "data: selector #log\n"},
+			absent: []string{"data: mode append"},
+		},
+		{
+			name:   "replace",
+			opts:   []PatchOption{WithMode(datastar.ElementPatchModeReplace)},
+			want:   []string{"data: mode replace\n"},
+			absent: []string{"data: selector"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			sse := datastar.NewSSE(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+			if err := Patch(sse, context.Background(), line, tt.opts...); err != nil {
+				t.Fatalf("Patch: %v", err)
+			}
+			body := rec.Body.String()
+			for _, want := range tt.want {
+				if !strings.Contains(body, want) {
+					t.Errorf("event lacks %q:\n%s", want, body)
+				}
+			}
+			for _, absent := range tt.absent {
+				if strings.Contains(body, absent) {
+					t.Errorf("event has %q:\n%s", absent, body)
+				}
+			}
+		})
+	}
+}
+
+func TestPatchInvalidModeSendsNothing(t *testing.T) {
+	rec := httptest.NewRecorder()
+	sse := datastar.NewSSE(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	err := Patch(sse, context.Background(), templ.Raw("<p>x</p>"), WithMode("sideways"))
+	if err == nil || !strings.Contains(err.Error(), "invalid patch mode") {
+		t.Fatalf("Patch = %v, want an invalid mode error", err)
+	}
+	if strings.Contains(rec.Body.String(), "datastar-patch-elements") {
+		t.Fatalf("sent an event for an invalid mode:\n%s", rec.Body)
+	}
+}
@@ -0,0 +1,63 @@
+package sseutil
+
+import (
+	"net/http"
+	"strings"
+)
+
+// notSSEMessage tells a client that reached an SSE endpoint without asking
+// for a stream what to send instead.
+const notSSEMessage = "Bad Request: this endpoint responds with Server-Sent Events. " +
+	"Send it from Datastar (which sets Datastar-Request: true) or with Accept: text/event-stream."
+
+// IsSSERequest reports whether r asked for an event stream: Datastar sets
+// Datastar-Request, and EventSource and other SSE clients accept
+// text/event-stream.
+func IsSSERequest(r *http.Request) bool {
+	return r.Header.Get("Datastar-Request") == "true" ||
+		strings.Contains(r.Header.Get("Accept"), "text/event-stream")
+}
+
+// Check reports whether a stream can be opened for r on w. If not, it
+// answers 400 with guidance when r did not ask for a stream, or 500 when w
+// cannot flush, and returns false. Nothing is written when it returns true,
+// so the caller may still reject the request in other ways.
+func Check(w http.ResponseWriter, r *http.Request) bool {
+	if !IsSSERequest(r) {
+		http.Error(w, notSSEMessage, http.StatusBadRequest)
+		return false
+	}
+	if !canFlush(w) {
+		http.Error(w, "Internal Server Error: response cannot be streamed", http.StatusInternalServerError)
+		return false
+	}
+	return true
+}
+
+// canFlush reports whether w, or a writer it wraps, can flush, the same
+// way http.ResponseController finds one.
+func canFlush(w http.ResponseWriter) bool {
+	for {
+		switch t := w.(type) {
+		case interface{ FlushError() error }, http.Flusher:
+			return true
+		case interface{ Unwrap() http.ResponseWriter }:
+			w = t.Unwrap()
+		default:
+			return false
+		}
+	}
+}
+
+// Require answers requests that cannot take an event stream as Check does,
+// before next runs. Wrap routes that only ever respond with SSE in it so a
+// stray request has no side effects; handlers that also serve JSON call
+// Start instead.
+func Require(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !Check(w, r) {
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,42 @@
+package sseutil
+
+import (
+	"sync/atomic"
+	"time"
+
+	"github.com/starfederation/datastar-go/datastar"
+)
+
+// RetryPolicy picks the SSE reconnect delay sent to clients of long-lived
+// streams. The delay starts at Base and doubles for every StreamsPerStep
+// streams currently open, up to Max, so reconnect storms back off as load
+// grows.
+type RetryPolicy struct {
+	Base           time.Duration
+	Max            time.Duration
+	StreamsPerStep int
+
+	open atomic.Int64
+}
+
+// Track counts a stream as open until the returned function is called.
+func (p *RetryPolicy) Track() (done func()) {
+	p.open.Add(1)
+	return func() { p.open.Add(-1) }
+}
+
+// Delay returns the reconnect delay for the current load.
+func (p *RetryPolicy) Delay() time.Duration {
+	step := max(p.StreamsPerStep, 1)
+	delay := p.Base
+	for n := p.open.Load() / int64(step); n > 0 && delay < p.Max; n-- {
+		delay *= 2
+	}
+	return min(delay, p.Max)
+}
+
+// Retry sends a retry directive setting the client's reconnect delay. It
+// rides on an empty signal patch, which clients otherwise ignore.
+func Retry(sse *datastar.ServerSentEventGenerator, d time.Duration) error {
+	return sse.PatchSignals([]byte("{}"), datastar.WithPatchSignalsRetryDuration(d))
+}
@@ -0,0 +1,15 @@
+package sseutil
+
+import (
+	"github.com/ankit-lilly/go-datastar-daisyui-template/internal/views"
+	"github.com/starfederation/datastar-go/datastar"
+)
+
+// Toast appends a self-dismissing toast to the layout's toast container.
+// Toasts are appended rather than replaced so several can stack.
+func Toast(sse *datastar.ServerSentEventGenerator, level, message string) error {
+	return Patch(sse, sse.Context(), views.Toast(level, message),
+		WithTarget(views.ToastContainerID),
+		WithMode(datastar.ElementPatchModeAppend),
+	)
+}
@@ -0,0 +1,103 @@
+package sseutil
+
+import (
+	"context"
+	"log/slog"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/starfederation/datastar-go/datastar"
+)
+
+// StreamFunc drives one long-lived stream. ctx ends when the client goes
+// away or stops reading; the stream closes when the function returns.
+type StreamFunc func(ctx context.Context, sse *datastar.ServerSentEventGenerator) error
+
+// Streams holds what every long-lived SSE endpoint shares: the cap on open
+// streams, the reconnect policy, the write deadline and the heartbeat.
+type Streams struct {
+	Limiter *Limiter
+	Retry   *RetryPolicy
+	// WriteTimeout bounds each event; see NewSSE.
+	WriteTimeout time.Duration
+	// Heartbeat, when positive, sends an empty signal patch that often, so
+	// idle proxies keep the connection and dead clients are noticed.
+	Heartbeat time.Duration
+	Logger    *slog.Logger
+}
+
+// Handle wraps fn in the setup and teardown every stream needs. It rejects
+// requests that aren't SSE (see Check), answers 503 with Retry-After when
+// the limiter is full, opens the stream with the write deadline, sends the
+// reconnect delay, and runs the heartbeat until fn returns. An error from
+// fn is logged, at debug level if the client had already gone.
+func (s *Streams) Handle(fn StreamFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !Check(w, r) {
+			return
+		}
+		if !s.Limiter.Acquire() {
+			s.Logger.Warn("too many open streams, rejecting", "path", r.URL.Path)
+			retry := int(math.Ceil(s.Retry.Delay().Seconds()))
+			w.Header().Set("Retry-After", strconv.Itoa(max(retry, 1)))
+			http.Error(w, "Service Unavailable: too many open streams", http.StatusServiceUnavailable)
+			return
+		}
+		defer s.Limiter.Release()
+
+		sse := NewSSE(w, r, s.WriteTimeout)
+		if err := Retry(sse, s.Retry.Delay()); err != nil {
+			s.Logger.Debug("failed to send retry hint", "error", err)
+		}
+		defer s.Retry.Track()()
+
+		ctx, stop := context.WithCancel(sse.Context())
+		defer stop()
+		if s.Heartbeat > 0 {
+			// The heartbeat must not write once the handler has returned.
+			var wg sync.WaitGroup
+			defer wg.Wait()
+			defer stop()
+			wg.Go(func() { s.heartbeat(ctx, stop, sse) })
+		}
+
+		if err := fn(ctx, sse); err != nil {
+			if sse.IsClosed() {
+				s.Logger.Debug("stream ended after client left", "path", r.URL.Path, "error", err)
+				return
+			}
+			s.Logger.Error("stream failed", "path", r.URL.Path, "error", err)
+		}
+	}
+}
+
+// Start checks r as Check does and opens a short-lived stream with the
+// write deadline, for handlers that send a few events and return. It
+// returns false if the request was answered with an error instead.
+func (s *Streams) Start(w http.ResponseWriter, r *http.Request) (*datastar.ServerSentEventGenerator, bool) {
+	if !Check(w, r) {
+		return nil, false
+	}
+	return NewSSE(w, r, s.WriteTimeout), true
+}
+
+// heartbeat rides on the same empty signal patch as Retry, which clients
+// ignore. A failed write ends the stream.
+func (s *Streams) heartbeat(ctx context.Context, stop context.CancelFunc, sse *datastar.ServerSentEventGenerator) {
+	ticker := time.NewTicker(s.Heartbeat)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := sse.PatchSignals([]byte("{}")); err != nil {
+				stop()
+				return
+			}
+		}
+	}
+}
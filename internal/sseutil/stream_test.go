@@ -0,0 +1,200 @@
+package sseutil
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/starfederation/datastar-go/datastar"
+)
+
+func newTestStreams(limit int) *Streams {
+	return &Streams{
+		Limiter: NewLimiter(limit),
+		Retry:   &RetryPolicy{Base: time.Second, Max: time.Second},
+		Logger:  slog.New(slog.DiscardHandler),
+	}
+}
+
+// openStream starts a stream request and, if it was accepted, waits for
+// its first event. The caller cancels ctx to close it.
+func openStream(t *testing.T, ctx context.Context, url string) *http.Response {
+	t.Helper()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Datastar-Request", "true")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { resp.Body.Close() })
+	if resp.StatusCode == http.StatusOK {
+		if _, err := bufio.NewReader(resp.Body).ReadString('\n'); err != nil {
+			t.Fatalf("reading first event: %v", err)
+		}
+	}
+	return resp
+}
+
+func TestStreamsLimit(t *testing.T) {
+	const limit = 2
+	streams := newTestStreams(limit)
+	srv := httptest.NewServer(streams.Handle(func(ctx context.Context, _ *datastar.ServerSentEventGenerator) error {
+		<-ctx.Done()
+		return nil
+	}))
+	defer srv.Close()
+
+	cancels := make([]context.CancelFunc, limit)
+	for i := range limit {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		cancels[i] = cancel
+		if resp := openStream(t, ctx, srv.URL); resp.StatusCode != http.StatusOK {
+			t.Fatalf("stream %d: status %d, want 200", i+1, resp.StatusCode)
+		}
+	}
+
+	resp := openStream(t, context.Background(), srv.URL)
+	if resp.StatusCode != http.StatusServiceUnavailable {
+		t.Fatalf("stream %d: status %d, want 503", limit+1, resp.StatusCode)
+	}
+	if got := resp.Header.Get("Retry-After"); got != "1" {
+		t.Fatalf("Retry-After = %q, want 1", got)
+	}
+
+	// Closing a stream frees its slot once the handler has returned.
+	cancels[0]()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		resp := openStream(t, ctx, srv.URL)
+		if resp.StatusCode == http.StatusOK {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("status %d after a stream closed, want 200", resp.StatusCode)
+		}
+		cancel()
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestStreamsRejectsNonSSE(t *testing.T) {
+	streams := newTestStreams(1)
+	called := false
+	handler := streams.Handle(func(context.Context, *datastar.ServerSentEventGenerator) error {
+		called = true
+		return nil
+	})
+
+	rec := httptest.NewRecorder()
+	handler(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	if rec.Code != http.StatusBadRequest || called {
+		t.Fatalf("status %d, called %v; want 400 without running", rec.Code, called)
+	}
+	if !streams.Limiter.Acquire() {
+		t.Fatal("rejected request kept a limiter slot")
+	}
+}
+
+// logBuffer collects log output written from server goroutines.
+type logBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *logBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+// waitFor waits until the log contains substr.
+func (b *logBuffer) waitFor(t *testing.T, substr string) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		b.mu.Lock()
+		out := b.buf.String()
+		b.mu.Unlock()
+		if strings.Contains(out, substr) {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("log lacks %q:\n%s", substr, out)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestStreamsHandleEnd(t *testing.T) {
+	tests := []struct {
+		name string
+		fn   StreamFunc
+		// leave closes the client side once the stream is open.
+		leave bool
+		want  string
+	}{
+		{
+			name: "fn fails",
+			fn: func(context.Context, *datastar.ServerSentEventGenerator) error {
+				return errors.New("backend down")
+			},
+			want: `level=ERROR msg="stream failed" path=/ error="backend down"`,
+		},
+		{
+			name: "client leaves",
+			fn: func(ctx context.Context, _ *datastar.ServerSentEventGenerator) error {
+				<-ctx.Done()
+				return ctx.Err()
+			},
+			leave: true,
+			want:  `level=DEBUG msg="stream ended after client left" path=/`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logs := &logBuffer{}
+			streams := newTestStreams(1)
+			streams.Logger = slog.New(slog.NewTextHandler(logs, &slog.HandlerOptions{Level: slog.LevelDebug}))
+			srv := httptest.NewServer(streams.Handle(tt.fn))
+			defer srv.Close()
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			resp := openStream(t, ctx, srv.URL)
+			if resp.StatusCode != http.StatusOK {
+				t.Fatalf("status %d, want 200", resp.StatusCode)
+			}
+			if tt.leave {
+				cancel()
+			} else {
+				// The stream closes by itself once fn returns.
+				io.Copy(io.Discard, resp.Body)
+			}
+
+			logs.waitFor(t, tt.want)
+			// The slot is released once the handler has returned.
+			deadline := time.Now().Add(5 * time.Second)
+			for !streams.Limiter.Acquire() {
+				if time.Now().After(deadline) {
+					t.Fatal("ended stream kept its limiter slot")
+				}
+				time.Sleep(5 * time.Millisecond)
+			}
+		})
+	}
+}
@@ -0,0 +1,165 @@
+// Package store provides a small concurrency-safe in-memory key/value store
+// for demo state, with optional per-key expiry.
+package store
+
+import (
+	"sync"
+	"time"
+)
+
+type entry[V any] struct {
+	value   V
+	expires time.Time // zero means never
+}
+
+func (e entry[V]) expired(now time.Time) bool {
+	return !e.expires.IsZero() && !now.Before(e.expires)
+}
+
+// Store is a mutex-guarded map. Entries set with a TTL disappear from Get
+// and Range once expired; Run removes them from memory.
+type Store[K comparable, V any] struct {
+	ttl   time.Duration
+	items map[K]entry[V]
+	done  chan struct{}
+	mu    sync.RWMutex
+}
+
+// New returns a store whose Set and LoadOrStore use ttl as the expiry.
+// A ttl of zero keeps entries until they are deleted.
+func New[K comparable, V any](ttl time.Duration) *Store[K, V] {
+	return &Store[K, V]{
+		ttl:   ttl,
+		items: make(map[K]entry[V]),
+		done:  make(chan struct{}),
+	}
+}
+
+func (s *Store[K, V]) expiry(ttl time.Duration) time.Time {
+	if ttl <= 0 {
+		return time.Time{}
+	}
+	return time.Now().Add(ttl)
+}
+
+func (s *Store[K, V]) Get(key K) (V, bool) {
+	s.mu.RLock()
+	e, ok := s.items[key]
+	s.mu.RUnlock()
+	if !ok || e.expired(time.Now()) {
+		var zero V
+		return zero, false
+	}
+	return e.value, true
+}
+
+// Set stores value under key with the store's default TTL.
+func (s *Store[K, V]) Set(key K, value V) {
+	s.mu.Lock()
+	s.items[key] = entry[V]{value: value, expires: s.expiry(s.ttl)}
+	s.mu.Unlock()
+}
+
+// SetDefaultTTL changes the TTL used by Set, LoadOrStore and Touch from now
+// on. Entries already stored keep their expiry.
+func (s *Store[K, V]) SetDefaultTTL(ttl time.Duration) {
+	s.mu.Lock()
+	s.ttl = ttl
+	s.mu.Unlock()
+}
+
+// SetTTL stores value under key, expiring after ttl. A ttl of zero never
+// expires.
+func (s *Store[K, V]) SetTTL(key K, value V, ttl time.Duration) {
+	s.mu.Lock()
+	s.items[key] = entry[V]{value: value, expires: s.expiry(ttl)}
+	s.mu.Unlock()
+}
+
+// LoadOrStore returns the live value for key if there is one. Otherwise it
+// stores value with the default TTL and returns it. loaded reports which.
+func (s *Store[K, V]) LoadOrStore(key K, value V) (actual V, loaded bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if e, ok := s.items[key]; ok && !e.expired(time.Now()) {
+		return e.value, true
+	}
+	s.items[key] = entry[V]{value: value, expires: s.expiry(s.ttl)}
+	return value, false
+}
+
+// Touch restarts the default TTL of a live key and reports whether it was
+// found.
+func (s *Store[K, V]) Touch(key K) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e, ok := s.items[key]
+	if !ok || e.expired(time.Now()) {
+		return false
+	}
+	e.expires = s.expiry(s.ttl)
+	s.items[key] = e
+	return true
+}
+
+func (s *Store[K, V]) Delete(key K) {
+	s.mu.Lock()
+	delete(s.items, key)
+	s.mu.Unlock()
+}
+
+// Range calls fn for each live entry until fn returns false. It iterates
+// over a snapshot, so fn may modify the store.
+func (s *Store[K, V]) Range(fn func(key K, value V) bool) {
+	now := time.Now()
+	s.mu.RLock()
+	live := make(map[K]V, len(s.items))
+	for k, e := range s.items {
+		if !e.expired(now) {
+			live[k] = e.value
+		}
+	}
+	s.mu.RUnlock()
+
+	for k, v := range live {
+		if !fn(k, v) {
+			return
+		}
+	}
+}
+
+// Len returns the number of entries, including expired ones Run has not
+// removed yet.
+func (s *Store[K, V]) Len() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.items)
+}
+
+// Run removes expired entries every interval until Stop is called.
+func (s *Store[K, V]) Run(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			s.removeExpired(time.Now())
+		case <-s.done:
+			return
+		}
+	}
+}
+
+func (s *Store[K, V]) Stop() {
+	close(s.done)
+}
+
+func (s *Store[K, V]) removeExpired(now time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for k, e := range s.items {
+		if e.expired(now) {
+			delete(s.items, k)
+		}
+	}
+}
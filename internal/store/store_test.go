@@ -0,0 +1,129 @@
+package store
+
+import (
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestSetGetDelete(t *testing.T) {
+	s := New[string, int](0)
+	if _, ok := s.Get("a"); ok {
+		t.Fatal("Get on empty store found a value")
+	}
+	s.Set("a", 1)
+	if v, ok := s.Get("a"); !ok || v != 1 {
+		t.Fatalf("Get = %d, %v; want 1, true", v, ok)
+	}
+	s.Delete("a")
+	if _, ok := s.Get("a"); ok {
+		t.Fatal("Get found a deleted value")
+	}
+}
+
+func TestTTLExpiry(t *testing.T) {
+	s := New[string, int](time.Hour)
+	s.SetTTL("short", 1, time.Millisecond)
+	s.SetTTL("forever", 2, 0)
+	s.Set("default", 3)
+	time.Sleep(5 * time.Millisecond)
+
+	if _, ok := s.Get("short"); ok {
+		t.Error("expired entry still returned by Get")
+	}
+	for _, key := range []string{"forever", "default"} {
+		if _, ok := s.Get(key); !ok {
+			t.Errorf("%s: live entry missing", key)
+		}
+	}
+	s.Range(func(key string, _ int) bool {
+		if key == "short" {
+			t.Error("Range visited an expired entry")
+		}
+		return true
+	})
+
+	if n := s.Len(); n != 3 {
+		t.Fatalf("Len before cleanup = %d, want 3", n)
+	}
+	s.removeExpired(time.Now())
+	if n := s.Len(); n != 2 {
+		t.Fatalf("Len after cleanup = %d, want 2", n)
+	}
+}
+
+func TestLoadOrStore(t *testing.T) {
+	s := New[string, int](time.Hour)
+	if v, loaded := s.LoadOrStore("a", 1); loaded || v != 1 {
+		t.Fatalf("first LoadOrStore = %d, %v; want 1, false", v, loaded)
+	}
+	if v, loaded := s.LoadOrStore("a", 2); !loaded || v != 1 {
+		t.Fatalf("second LoadOrStore = %d, %v; want 1, true", v, loaded)
+	}
+
+	// An expired entry is replaced, not loaded.
+	s.SetTTL("b", 1, time.Millisecond)
+	time.Sleep(5 * time.Millisecond)
+	if v, loaded := s.LoadOrStore("b", 2); loaded || v != 2 {
+		t.Fatalf("LoadOrStore over expired = %d, %v; want 2, false", v, loaded)
+	}
+}
+
+func TestTouch(t *testing.T) {
+	s := New[string, int](200 * time.Millisecond)
+	s.Set("a", 1)
+	time.Sleep(120 * time.Millisecond)
+	if !s.Touch("a") {
+		t.Fatal("Touch missed a live key")
+	}
+	time.Sleep(120 * time.Millisecond)
+	if _, ok := s.Get("a"); !ok {
+		t.Fatal("touched entry expired on its original deadline")
+	}
+
+	if s.Touch("missing") {
+		t.Error("Touch found a missing key")
+	}
+	s.SetTTL("gone", 1, time.Millisecond)
+	time.Sleep(5 * time.Millisecond)
+	if s.Touch("gone") {
+		t.Error("Touch revived an expired key")
+	}
+}
+
+func TestConcurrentAccess(t *testing.T) {
+	s := New[string, int](time.Hour)
+	go s.Run(time.Millisecond)
+	defer s.Stop()
+
+	var wg sync.WaitGroup
+	for g := range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range 500 {
+				key := strconv.Itoa(i % 20)
+				switch (g + i) % 6 {
+				case 0:
+					s.Set(key, i)
+				case 1:
+					s.Get(key)
+				case 2:
+					s.LoadOrStore(key, i)
+				case 3:
+					s.Touch(key)
+				case 4:
+					s.Delete(key)
+				case 5:
+					s.Range(func(string, int) bool { return true })
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	if n := s.Len(); n > 20 {
+		t.Fatalf("Len = %d, want at most 20 keys", n)
+	}
+}
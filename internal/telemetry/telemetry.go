@@ -0,0 +1,67 @@
+// Package telemetry configures OpenTelemetry tracing from the standard
+// OTEL_* environment variables.
+package telemetry
+
+import (
+	"context"
+	"os"
+	"strings"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.43.0"
+)
+
+// DefaultServiceName is used when OTEL_SERVICE_NAME is not set.
+const DefaultServiceName = "go-datastar-daisyui-template"
+
+// Setup installs the global W3C trace-context propagator and, when an OTLP
+// endpoint is configured (OTEL_EXPORTER_OTLP_ENDPOINT or
+// OTEL_EXPORTER_OTLP_TRACES_ENDPOINT), a tracer provider exporting over
+// OTLP/HTTP. Otherwise tracing stays a no-op. The returned function flushes
+// and stops the provider.
+func Setup(ctx context.Context) (shutdown func(context.Context) error, enabled bool, err error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+
+	noop := func(context.Context) error { return nil }
+	if !configured() {
+		return noop, false, nil
+	}
+
+	exporter, err := otlptracehttp.New(ctx)
+	if err != nil {
+		return noop, false, err
+	}
+
+	res := resource.Default()
+	if os.Getenv("OTEL_SERVICE_NAME") == "" {
+		res, err = resource.Merge(res, resource.NewSchemaless(semconv.ServiceName(DefaultServiceName)))
+		if err != nil {
+			return noop, false, err
+		}
+	}
+
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+	)
+	otel.SetTracerProvider(tp)
+	return tp.Shutdown, true, nil
+}
+
+func configured() bool {
+	if strings.EqualFold(os.Getenv("OTEL_SDK_DISABLED"), "true") {
+		return false
+	}
+	if exp := os.Getenv("OTEL_TRACES_EXPORTER"); exp != "" && exp != "otlp" {
+		return false
+	}
+	return os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT") != "" ||
+		os.Getenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT") != ""
+}
@@ -0,0 +1,36 @@
+package views
+
+import (
+	"sync/atomic"
+
+	"github.com/ankit-lilly/go-datastar-daisyui-template/internal/assets"
+)
+
+var (
+	assetManifest atomic.Pointer[assets.Manifest]
+	assetPrefix   atomic.Pointer[string]
+)
+
+// SetAssetManifest installs the manifest used by AssetPath.
+func SetAssetManifest(m assets.Manifest) {
+	assetManifest.Store(&m)
+}
+
+// SetAssetPrefix sets the URL prefix static assets are served under, e.g.
+// "/static/".
+func SetAssetPrefix(prefix string) {
+	assetPrefix.Store(&prefix)
+}
+
+// AssetPath returns the public URL for a static asset, using its
+// fingerprinted name when the manifest has one.
+func AssetPath(name string) string {
+	if m := assetManifest.Load(); m != nil {
+		name = m.Resolve(name)
+	}
+	prefix := "/static/"
+	if p := assetPrefix.Load(); p != nil {
+		prefix = *p
+	}
+	return prefix + name
+}
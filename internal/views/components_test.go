@@ -0,0 +1,86 @@
+package views
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/a-h/templ"
+	"github.com/ankit-lilly/go-datastar-daisyui-template/internal/config"
+)
+
+// render renders c with ctx and returns the HTML.
+func render(t *testing.T, ctx context.Context, c templ.Component) string {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := c.Render(ctx, &buf); err != nil {
+		t.Fatalf("render: %v", err)
+	}
+	return buf.String()
+}
+
+func TestLayoutLoadsDatastarOnce(t *testing.T) {
+	html := render(t, context.Background(), IndexPage(IndexContent(true)))
+	if n := strings.Count(html, `src="`+AssetPath("js/datastar.js")+`"`); n != 1 {
+		t.Fatalf("datastar script included %d times, want 1", n)
+	}
+}
+
+func TestUserTextIsEscaped(t *testing.T) {
+	const input = `<script>alert("x")</script>`
+	tests := []struct {
+		name string
+		c    templ.Component
+	}{
+		{"SafeText", SafeText("note", input)},
+		{"ChatMessage", ChatMessage(ChatEntry{Text: input})},
+		{"Card", Card(input, input)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			html := render(t, context.Background(), tt.c)
+			if strings.Contains(html, "<script") {
+				t.Fatalf("input rendered as markup:\n%s", html)
+			}
+			if !strings.Contains(html, "&lt;script&gt;") {
+				t.Fatalf("escaped input missing:\n%s", html)
+			}
+		})
+	}
+}
+
+func TestNavMenuMarksCurrentItem(t *testing.T) {
+	items := []config.NavItem{
+		{Label: "Home", Href: "/"},
+		{Label: "Counter", Href: "/#counter-demo"},
+		{Label: "Reports", Href: "/reports"},
+		{Label: "Report archive", Href: "/reports-archive"},
+		{Label: "Docs", Href: "https://data-star.dev/guide"},
+	}
+	tests := []struct {
+		path string
+		want string // label of the active item, or "" for none
+	}{
+		{"/", "Home"},
+		{"/reports", "Reports"},
+		{"/reports/2026", "Reports"},
+		{"/reports-archive", "Report archive"},
+		{"/settings", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			ctx := WithNav(context.Background(), items, tt.path)
+			if html := render(t, ctx, NavMenu(items)); strings.Count(html, `aria-current="page"`) > 1 {
+				t.Fatalf("more than one item marked current:\n%s", html)
+			}
+			for _, item := range items {
+				html := render(t, ctx, NavMenu([]config.NavItem{item}))
+				active := strings.Contains(html, `aria-current="page"`) && strings.Contains(html, "menu-active")
+				if want := item.Label == tt.want; active != want {
+					t.Errorf("%s active = %v, want %v", item.Label, active, want)
+				}
+			}
+		})
+	}
+}
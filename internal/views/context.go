@@ -0,0 +1,181 @@
+package views
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/a-h/templ"
+	"github.com/ankit-lilly/go-datastar-daisyui-template/internal/config"
+)
+
+type contextKey int
+
+const (
+	csrfTokenKey contextKey = iota
+	themeKey
+	navKey
+)
+
+// ThemeSystem follows the browser's prefers-color-scheme instead of pinning
+// a DaisyUI theme.
+const ThemeSystem = "system"
+
+type themeState struct {
+	current   string
+	available []string
+}
+
+// WithCSRFToken returns a copy of ctx carrying the request's CSRF token.
+func WithCSRFToken(ctx context.Context, token string) context.Context {
+	return context.WithValue(ctx, csrfTokenKey, token)
+}
+
+// CSRFToken returns the CSRF token for the current request, if any.
+func CSRFToken(ctx context.Context) string {
+	token, _ := ctx.Value(csrfTokenKey).(string)
+	return token
+}
+
+// WithNonce returns a copy of ctx carrying the request's CSP script nonce.
+// It is stored the way templ stores it, so script elements templ renders
+// itself get it too.
+func WithNonce(ctx context.Context, nonce string) context.Context {
+	return templ.WithNonce(ctx, nonce)
+}
+
+// Nonce returns the CSP script nonce for the current request, if any.
+func Nonce(ctx context.Context) string {
+	return templ.GetNonce(ctx)
+}
+
+// WithTheme returns a copy of ctx carrying the selected theme and the list
+// of themes the picker offers.
+func WithTheme(ctx context.Context, current string, available []string) context.Context {
+	return context.WithValue(ctx, themeKey, themeState{current: current, available: available})
+}
+
+// Theme returns the request's selected theme, or ThemeSystem.
+func Theme(ctx context.Context) string {
+	if t, ok := ctx.Value(themeKey).(themeState); ok && t.current != "" {
+		return t.current
+	}
+	return ThemeSystem
+}
+
+// Themes returns the themes offered by the picker.
+func Themes(ctx context.Context) []string {
+	t, _ := ctx.Value(themeKey).(themeState)
+	return t.available
+}
+
+type navState struct {
+	items []config.NavItem
+	path  string
+}
+
+// WithNav returns a copy of ctx carrying the navbar items and the request
+// path they are highlighted against.
+func WithNav(ctx context.Context, items []config.NavItem, path string) context.Context {
+	return context.WithValue(ctx, navKey, navState{items: items, path: path})
+}
+
+// NavItems returns the navbar items for the current request.
+func NavItems(ctx context.Context) []config.NavItem {
+	n, _ := ctx.Value(navKey).(navState)
+	return n.items
+}
+
+// navActive reports whether item links to the current page: its href is
+// the request path, or a parent of it other than "/". External links are
+// never active.
+func navActive(ctx context.Context, item config.NavItem) bool {
+	n, _ := ctx.Value(navKey).(navState)
+	if n.path == "" || !strings.HasPrefix(item.Href, "/") {
+		return false
+	}
+	href := strings.TrimSuffix(item.Href, "/")
+	return n.path == item.Href || (href != "" && strings.HasPrefix(n.path, href+"/"))
+}
+
+// navLinkAttrs marks the active item for DaisyUI and assistive technology,
+// and opens external links in a new tab.
+func navLinkAttrs(ctx context.Context, item config.NavItem) templ.Attributes {
+	attrs := templ.Attributes{}
+	switch {
+	case navActive(ctx, item):
+		attrs["class"] = "menu-active"
+		attrs["aria-current"] = "page"
+	case !strings.HasPrefix(item.Href, "/"):
+		attrs["target"] = "_blank"
+		attrs["rel"] = "noopener"
+	}
+	return attrs
+}
+
+// bodySignals seeds the page-wide local (underscore-prefixed) signals, which
+// are available to actions without being sent in the signals payload.
+func bodySignals(ctx context.Context) string {
+	b, _ := json.Marshal(map[string]string{
+		"_csrf":        CSRFToken(ctx),
+		"_theme":       Theme(ctx),
+		"themeRequest": "",
+	})
+	return string(b)
+}
+
+// themeEffect mirrors the $_theme signal onto <html data-theme>.
+const themeEffect = "$_theme == 'system' ? document.documentElement.removeAttribute('data-theme') : document.documentElement.setAttribute('data-theme', $_theme)"
+
+func htmlAttrs(ctx context.Context) templ.Attributes {
+	attrs := templ.Attributes{"lang": "en"}
+	if theme := Theme(ctx); theme != ThemeSystem {
+		attrs["data-theme"] = theme
+	}
+	return attrs
+}
+
+// setTheme builds the action that asks the server to switch to theme.
+func setTheme(theme string) string {
+	return fmt.Sprintf("$themeRequest = %s; %s", jsString(theme), post("/api/theme"))
+}
+
+func themeActive(theme string) string {
+	return fmt.Sprintf("$_theme == %s", jsString(theme))
+}
+
+// adminEnabled reports whether the admin routes are registered, which they
+// only are behind basic auth.
+func adminEnabled() bool {
+	cfg := config.Current()
+	return cfg != nil && cfg.BasicAuthUser != ""
+}
+
+// post builds a Datastar @post action that sends the CSRF token header.
+func post(url string) string {
+	return postExpr(jsString(url))
+}
+
+// postExpr is like post but takes a JavaScript expression for the URL, for
+// URLs built from signals.
+func postExpr(urlExpr string) string {
+	return fmt.Sprintf("@post(%s, {headers: {'X-CSRF-Token': $_csrf}})", urlExpr)
+}
+
+// postJob posts to /api/job/{$jobId}/{action}.
+func postJob(action string) string {
+	return postExpr("'/api/job/' + $jobId + " + jsString("/"+action))
+}
+
+// get builds a Datastar @get action. Safe methods need no CSRF header.
+func get(url string) string {
+	return fmt.Sprintf("@get(%s)", jsString(url))
+}
+
+// jsString quotes s as a JavaScript string literal for use inside Datastar
+// expressions. JSON encoding also escapes <, > and &.
+func jsString(s string) string {
+	b, _ := json.Marshal(s)
+	return string(b)
+}
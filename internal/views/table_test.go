@@ -0,0 +1,40 @@
+package views
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/a-h/templ"
+)
+
+func TestTable(t *testing.T) {
+	columns := append(Columns("Name", "Status"), TableColumn{Label: "Created", Action: "@get('/sort')"})
+	rows := [][]templ.Component{
+		{Text("a"), Text("running"), Text("now")},
+		{Text("b"), Text("done"), Text("then")},
+	}
+
+	html := render(t, context.Background(), Table(columns, rows, "Nothing yet"))
+	if n := strings.Count(html, "</th>"); n != 3 {
+		t.Errorf("%d header cells, want 3", n)
+	}
+	if n := strings.Count(html, "data-on:click="); n != 1 {
+		t.Errorf("%d clickable headers, want 1", n)
+	}
+	body := html[strings.Index(html, "<tbody>"):]
+	if n := strings.Count(body, "<tr>"); n != 2 {
+		t.Errorf("%d body rows, want 2", n)
+	}
+	if n := strings.Count(body, "<td>"); n != 6 {
+		t.Errorf("%d body cells, want 6", n)
+	}
+	if strings.Contains(html, "Nothing yet") {
+		t.Error("empty message shown with rows")
+	}
+
+	html = render(t, context.Background(), Table(columns, nil, "Nothing yet"))
+	if !strings.Contains(html, `colspan="3"`) || !strings.Contains(html, "Nothing yet") {
+		t.Errorf("empty table lacks a full-width message:\n%s", html)
+	}
+}